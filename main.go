@@ -39,6 +39,11 @@ const (
 )
 
 func main() {
+	// The command line modes of the plugin itself use flags unknown to the SDK, so they are handled first.
+	if handled, code := plugin.RunCLI(os.Args[1:], os.Stdout, os.Stderr); handled {
+		os.Exit(code)
+	}
+
 	err := flag.HandleFlags(
 		plugin.Name,
 		os.Args[0],
@@ -0,0 +1,412 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"golang.zabbix.com/plugin/mongodb/plugin/handlers"
+	"golang.zabbix.com/sdk/plugin"
+)
+
+const (
+	defaultConfigFile = "/etc/zabbix/zabbix_agent2.d/plugins.d/mongodb.conf"
+	defaultCLITimeout = 3
+	configPrefix      = "Plugins." + Name + "."
+)
+
+var errInvalidKey = errors.New("invalid item key")
+
+// cliContext is the request context of keys executed from the command line.
+type cliContext struct {
+	timeout int
+}
+
+func (*cliContext) ClientID() uint64                   { return 0 }
+func (*cliContext) ItemID() uint64                     { return 0 }
+func (*cliContext) Output() plugin.ResultWriter        { return nil }
+func (*cliContext) Meta() *plugin.Meta                 { return nil }
+func (*cliContext) GlobalRegexp() plugin.RegexpMatcher { return nil }
+func (c *cliContext) Timeout() int                     { return c.timeout }
+func (*cliContext) Delay() string                      { return "" }
+
+// cliLogger writes warnings of the plugin to the standard error. Errors are not logged,
+// as the error of the key is printed anyway.
+type cliLogger struct {
+	out io.Writer
+}
+
+func (*cliLogger) Infof(string, ...any)  {}
+func (*cliLogger) Errf(string, ...any)   {}
+func (*cliLogger) Debugf(string, ...any) {}
+func (*cliLogger) Tracef(string, ...any) {}
+
+func (l *cliLogger) Critf(format string, args ...any) {
+	fmt.Fprintf(l.out, "critical: "+format+"\n", args...)
+}
+
+func (l *cliLogger) Warningf(format string, args ...any) {
+	fmt.Fprintf(l.out, "warning: "+format+"\n", args...)
+}
+
+// RunCLI handles the command line modes that run the plugin without the agent.
+// It reports whether such a mode was requested and the exit code of the process,
+// the plugin is started by the agent otherwise.
+func RunCLI(args []string, stdout, stderr io.Writer) (bool, int) {
+	if !hasFlag(args, "t", "test", "check-config") {
+		return false, 0
+	}
+
+	var (
+		key       string
+		cfgFile   string
+		checkFile string
+		pretty    bool
+		connect   bool
+	)
+
+	fs := flag.NewFlagSet(Name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&key, "t", "", "execute the item key and print the result")
+	fs.StringVar(&key, "test", "", "execute the item key and print the result")
+	fs.StringVar(&cfgFile, "c", defaultConfigFile, "plugin configuration file")
+	fs.StringVar(&cfgFile, "config", defaultConfigFile, "plugin configuration file")
+	fs.BoolVar(&pretty, "p", false, "indent JSON results")
+	fs.BoolVar(&pretty, "pretty", false, "indent JSON results")
+	fs.StringVar(&checkFile, "check-config", "", "validate the plugin configuration file")
+	fs.BoolVar(&connect, "connect", false, "connect to every named session when checking the configuration")
+
+	err := fs.Parse(args)
+	if err != nil {
+		return true, 1
+	}
+
+	if checkFile != "" {
+		err = checkConfig(stdout, stderr, checkFile, connect)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", checkFile, err)
+
+			return true, 1
+		}
+
+		return true, 0
+	}
+
+	err = testKey(stdout, stderr, key, cfgFile, pretty)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %s\n", key, err)
+
+		return true, 1
+	}
+
+	return true, 0
+}
+
+// checkConfig validates the configuration file and optionally checks the connection to every named session.
+func checkConfig(stdout, stderr io.Writer, cfgFile string, connect bool) error {
+	options, err := readConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	p := &Impl
+	p.Logger = &cliLogger{out: stderr}
+
+	err = p.Validate(options)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	fmt.Fprintln(stdout, "configuration is valid")
+
+	if !connect {
+		return nil
+	}
+
+	p.Configure(&plugin.GlobalOptions{Timeout: defaultCLITimeout}, options)
+	p.startConnManager()
+
+	defer p.Stop()
+
+	opts := p.config()
+
+	names := make([]string, 0, len(opts.Sessions))
+	for name := range opts.Sessions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var failed []string
+
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SESSION\tREACHABLE\tAUTH\tVERSION\tROLE")
+
+	for _, name := range names {
+		res := p.checkSession(name, opts)
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", name, res.reachable, res.auth, res.version, res.role)
+
+		if res.err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", name, res.err))
+		}
+	}
+
+	w.Flush()
+
+	for _, f := range failed {
+		fmt.Fprintln(stderr, f)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d sessions failed", len(failed), len(names))
+	}
+
+	return nil
+}
+
+type sessionCheck struct {
+	reachable string
+	auth      string
+	version   string
+	role      string
+	err       error
+}
+
+// checkSession connects to the server of the named session and reports the first step that failed.
+func (p *Plugin) checkSession(name string, opts *PluginOptions) sessionCheck {
+	res := sessionCheck{reachable: "-", auth: "-", version: "-", role: "-"}
+
+	connURI, params, err := sessionParams(name, opts)
+	if err != nil {
+		res.err = err
+
+		return res
+	}
+
+	timeout := time.Duration(opts.Timeout) * time.Second
+
+	tcpConn, err := net.DialTimeout("tcp", connURI.Addr(), timeout)
+	if err != nil {
+		res.reachable, res.err = "no", err
+
+		return res
+	}
+
+	tcpConn.Close() //nolint:errcheck,gosec
+
+	res.reachable = "yes"
+
+	conn, err := p.connMgr.GetConnection(*connURI, params)
+	if err != nil {
+		res.auth, res.err = "failed", err
+
+		return res
+	}
+
+	defer p.connMgr.Release(conn)
+
+	res.auth = "ok"
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	version, err := handlers.VersionHandler(ctx, conn, nil)
+	if err != nil {
+		res.err = err
+
+		return res
+	}
+
+	res.version = fmt.Sprint(version)
+
+	res.role, err = handlers.ServerRole(ctx, conn)
+	if err != nil {
+		res.role, res.err = "-", err
+	}
+
+	return res
+}
+
+// testKey configures and starts the plugin with the configuration file, executes the key and prints the result.
+func testKey(stdout, stderr io.Writer, rawKey, cfgFile string, pretty bool) error {
+	key, params, err := parseItemKey(rawKey)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := metrics[key]; !ok {
+		return fmt.Errorf("unsupported key %s", key)
+	}
+
+	options, err := readConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	p := &Impl
+	p.Logger = &cliLogger{out: stderr}
+
+	err = p.Validate(options)
+	if err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	p.Configure(&plugin.GlobalOptions{Timeout: defaultCLITimeout}, options)
+	p.startConnManager()
+
+	defer p.Stop()
+
+	start := time.Now()
+
+	result, err := p.Export(key, params, &cliContext{timeout: p.config().Timeout})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(stdout, formatResult(result, pretty))
+	fmt.Fprintf(stderr, "%s took %s\n", key, time.Since(start).Round(time.Microsecond))
+
+	return nil
+}
+
+// readConfig reads the plugin options from the configuration file and returns them without the
+// "Plugins.MongoDB." prefix in the format accepted by conf.Unmarshal.
+func readConfig(file string) ([]byte, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read configuration file: %w", err)
+	}
+
+	var options bytes.Buffer
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+
+		if strings.HasPrefix(line, configPrefix) {
+			options.WriteString(strings.TrimPrefix(line, configPrefix))
+			options.WriteByte('\n')
+		}
+	}
+
+	return options.Bytes(), nil
+}
+
+func formatResult(result any, pretty bool) string {
+	s, ok := result.(string)
+	if !ok {
+		return fmt.Sprint(result)
+	}
+
+	if pretty {
+		var out bytes.Buffer
+
+		if json.Indent(&out, []byte(s), "", "  ") == nil {
+			return out.String()
+		}
+	}
+
+	return s
+}
+
+// parseItemKey splits an item key like key[param1,"quoted, param",param3] into the key name and parameters.
+func parseItemKey(s string) (string, []string, error) {
+	open := strings.IndexByte(s, '[')
+	if open == -1 {
+		if s == "" {
+			return "", nil, errInvalidKey
+		}
+
+		return s, nil, nil
+	}
+
+	if open == 0 || !strings.HasSuffix(s, "]") {
+		return "", nil, fmt.Errorf("%w %q", errInvalidKey, s)
+	}
+
+	var (
+		params []string
+		rest   = s[open+1 : len(s)-1]
+	)
+
+	for {
+		rest = strings.TrimLeft(rest, " ")
+
+		var param string
+
+		if strings.HasPrefix(rest, `"`) {
+			end := 1
+			for end < len(rest) && (rest[end] != '"' || rest[end-1] == '\\') {
+				end++
+			}
+
+			if end == len(rest) {
+				return "", nil, fmt.Errorf("%w %q: unterminated quoted parameter", errInvalidKey, s)
+			}
+
+			param = strings.ReplaceAll(rest[1:end], `\"`, `"`)
+			rest = strings.TrimLeft(rest[end+1:], " ")
+
+			if rest != "" && rest[0] != ',' {
+				return "", nil, fmt.Errorf("%w %q: unexpected characters after quoted parameter", errInvalidKey, s)
+			}
+		} else {
+			end := strings.IndexByte(rest, ',')
+			if end == -1 {
+				end = len(rest)
+			}
+
+			param, rest = rest[:end], rest[end:]
+		}
+
+		params = append(params, param)
+
+		if rest == "" {
+			return s[:open], params, nil
+		}
+
+		rest = rest[1:]
+	}
+}
+
+// hasFlag reports whether any of the given flags is set in the arguments.
+func hasFlag(args []string, names ...string) bool {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+
+		name := strings.SplitN(strings.TrimLeft(arg, "-"), "=", 2)[0]
+
+		for _, n := range names {
+			if name == n {
+				return true
+			}
+		}
+	}
+
+	return false
+}
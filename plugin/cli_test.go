@@ -0,0 +1,201 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"bytes"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.zabbix.com/plugin/mongodb/plugin/internal/tlstest"
+)
+
+func TestParseItemKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		key        string
+		wantKey    string
+		wantParams []string
+		wantErr    bool
+	}{
+		{"+noParams", "mongodb.ping", "mongodb.ping", nil, false},
+		{"+emptyBrackets", "mongodb.ping[]", "mongodb.ping", []string{""}, false},
+		{
+			"+params",
+			"mongodb.collection.stats[Prod,,,mydb, users]",
+			"mongodb.collection.stats",
+			[]string{"Prod", "", "", "mydb", "users"},
+			false,
+		},
+		{
+			"+quoted",
+			`mongodb.command[Prod, "{\"ping\": 1, \"comment\": \"a,b\"}" ,admin]`,
+			"mongodb.command",
+			[]string{"Prod", `{"ping": 1, "comment": "a,b"}`, "admin"},
+			false,
+		},
+		{"-empty", "", "", nil, true},
+		{"-noClosingBracket", "mongodb.ping[Prod", "", nil, true},
+		{"-noName", "[Prod]", "", nil, true},
+		{"-unterminatedQuote", `mongodb.ping["Prod]`, "", nil, true},
+		{"-charsAfterQuote", `mongodb.ping["Prod"x]`, "", nil, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			key, params, err := parseItemKey(tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseItemKey() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if key != tt.wantKey {
+				t.Errorf("parseItemKey() key = %q, want %q", key, tt.wantKey)
+			}
+
+			if diff := cmp.Diff(tt.wantParams, params); diff != "" {
+				t.Errorf("parseItemKey() params = %s", diff)
+			}
+		})
+	}
+}
+
+//nolint:paralleltest // the command line mode configures the global plugin instance
+func TestRunCLI(t *testing.T) {
+	dir := t.TempDir()
+
+	config := tlstest.WriteFile(t, dir, "mongodb.conf", []byte(strings.Join([]string{
+		"# Plugins.MongoDB.Timeout=5",
+		"Plugins.MongoDB.Sessions.Prod.Uri=tcp://127.0.0.1:1",
+		"Plugins.Other.Option=value",
+		"Include=/etc/zabbix/other.conf",
+	}, "\n")))
+	invalid := tlstest.WriteFile(t, dir, "invalid.conf", []byte("Plugins.MongoDB.Sessions.Prod.TLSConnect=always\n"))
+
+	// A listener that is not a MongoDB server: reachable, but the connection cannot be established.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			conn.Close()
+		}
+	}()
+
+	sessions := tlstest.WriteFile(t, dir, "sessions.conf", []byte(strings.Join([]string{
+		"Plugins.MongoDB.Timeout=1",
+		"Plugins.MongoDB.Sessions.Down.Uri=tcp://127.0.0.1:1",
+		"Plugins.MongoDB.Sessions.NotMongo.Uri=tcp://" + ln.Addr().String(),
+	}, "\n")))
+
+	tests := []struct {
+		name        string
+		args        []string
+		wantHandled bool
+		wantCode    int
+		wantStdout  string
+		wantStderr  string
+	}{
+		{"+notCLI", []string{"/tmp/agent.sock"}, false, 0, "", ""},
+		{"+version", []string{"-V"}, false, 0, "", ""},
+		{
+			"+pluginStats",
+			[]string{"-t", "mongodb.plugin.stats", "-c", config},
+			true, 0, `{"connections":{"open":0,`, "mongodb.plugin.stats took",
+		},
+		{
+			"+pretty",
+			[]string{"--test=mongodb.plugin.stats", "--config", config, "--pretty"},
+			true, 0, "{\n  \"connections\": {", "",
+		},
+		{
+			"-unsupportedKey",
+			[]string{"-t", "mongodb.unknown[Prod]", "-c", config},
+			true, 1, "", "unsupported key mongodb.unknown",
+		},
+		{
+			"-tooManyParams",
+			[]string{"-t", "mongodb.plugin.stats[Prod]", "-c", config},
+			true, 1, "", "mongodb.plugin.stats[Prod]:",
+		},
+		{
+			"-missingConfig",
+			[]string{"-t", "mongodb.ping", "-c", filepath.Join(dir, "missing.conf")},
+			true, 1, "", "cannot read configuration file",
+		},
+		{
+			"-invalidConfig",
+			[]string{"-t", "mongodb.ping", "-c", invalid},
+			true, 1, "", "invalid configuration: invalid session Prod",
+		},
+		{
+			"+checkConfig",
+			[]string{"--check-config", config},
+			true, 0, "configuration is valid\n", "",
+		},
+		{
+			"-checkConfigInvalid",
+			[]string{"--check-config=" + invalid},
+			true, 1, "", "invalid configuration: invalid session Prod: incorrect tls connection type always",
+		},
+		{
+			"-checkConfigConnect",
+			[]string{"--check-config", sessions, "--connect"},
+			true, 1,
+			"configuration is valid\n" +
+				"SESSION   REACHABLE  AUTH    VERSION  ROLE\n" +
+				"Down      no         -       -        -\n" +
+				"NotMongo  yes        failed  -        -\n",
+			"2 of 2 sessions failed",
+		},
+		{"-unknownFlag", []string{"-t", "mongodb.ping", "--unknown"}, true, 1, "", "flag provided but not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+
+			handled, code := RunCLI(tt.args, &stdout, &stderr)
+			if handled != tt.wantHandled || code != tt.wantCode {
+				t.Fatalf("RunCLI() = %v, %d, want %v, %d (stderr: %s)",
+					handled, code, tt.wantHandled, tt.wantCode, stderr.String())
+			}
+
+			if !strings.HasPrefix(stdout.String(), tt.wantStdout) {
+				t.Errorf("RunCLI() stdout = %q, want prefix %q", stdout.String(), tt.wantStdout)
+			}
+
+			if !strings.Contains(stderr.String(), tt.wantStderr) {
+				t.Errorf("RunCLI() stderr = %q, want %q", stderr.String(), tt.wantStderr)
+			}
+		})
+	}
+}
@@ -15,10 +15,19 @@
 package plugin
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
 
+	"golang.zabbix.com/plugin/mongodb/plugin/handlers"
 	"golang.zabbix.com/sdk/conf"
+	"golang.zabbix.com/sdk/metric"
 	"golang.zabbix.com/sdk/plugin"
+	"golang.zabbix.com/sdk/uri"
 )
 
 const (
@@ -38,6 +47,32 @@ type Session struct {
 	TLSCAFile   string `conf:"name=TLSCAFile,optional"`
 	TLSCertFile string `conf:"name=TLSCertFile,optional"`
 	TLSKeyFile  string `conf:"name=TLSKeyFile,optional"`
+
+	// TLSMinVersion is the minimum TLS version: 1.0, 1.1, 1.2 or 1.3.
+	TLSMinVersion string `conf:"name=TLSMinVersion,optional"`
+	// TLSCipherSuites is a comma separated list of allowed TLS 1.0-1.2 cipher suites.
+	TLSCipherSuites string `conf:"name=TLSCipherSuites,optional"`
+	// TLSServerName overrides the server name used for SNI and certificate verification.
+	TLSServerName string `conf:"name=TLSServerName,optional"`
+	// TLSKeyPassword decrypts an encrypted TLSKeyFile.
+	TLSKeyPassword string `conf:"name=TLSKeyPassword,optional"`
+	// TLSCRLFile is a certificate revocation list the server certificate is checked against.
+	TLSCRLFile string `conf:"name=TLSCRLFile,optional"`
+
+	// Profile is the name of a profile the session inherits the options it does not set from.
+	Profile string `conf:"optional"`
+}
+
+// ExporterOptions configures the OpenMetrics endpoint.
+type ExporterOptions struct {
+	// Listen is the address of the HTTP endpoint, the exporter is disabled if empty.
+	Listen string `conf:"optional"`
+
+	// Sessions is a comma separated list of exported named sessions, all of them are exported if empty.
+	Sessions string `conf:"optional"`
+
+	// CollectionStats enables metrics of every collection.
+	CollectionStats bool `conf:"optional"`
 }
 
 type PluginOptions struct {
@@ -54,18 +89,147 @@ type PluginOptions struct {
 
 	// Default stores default connection parameter values from configuration file
 	Default Session `conf:"optional"`
+
+	// Profiles stores shared sets of connection settings which sessions refer to by name.
+	Profiles map[string]Session `conf:"optional"`
+
+	// CustomQueriesPath is a directory with named aggregation pipelines and commands
+	// used by the mongodb.custom.query key.
+	CustomQueriesPath string `conf:"optional"`
+
+	// BaselinesPath is a directory with the baseline files of the mongodb.rs.config.drift key
+	// and the expected values files of the mongodb.parameters.drift key.
+	BaselinesPath string `conf:"optional"`
+
+	// AllowedCommands is a comma separated list of commands the mongodb.command and mongodb.custom.query keys may run.
+	// Read-only diagnostic commands are allowed if empty.
+	AllowedCommands string `conf:"optional"`
+
+	// JSONMode selects how documents returned by the server are rendered as JSON:
+	// legacy, canonical, relaxed or zabbix. Legacy is used if empty.
+	JSONMode string `conf:"optional"`
+
+	// Exporter configures the OpenMetrics endpoint.
+	Exporter ExporterOptions `conf:"optional"`
 }
 
 // Configure implements the Configurator interface.
 // Initializes configuration structures.
 func (p *Plugin) Configure(global *plugin.GlobalOptions, options any) {
-	if err := conf.UnmarshalStrict(options, &p.options); err != nil {
+	var opts PluginOptions
+
+	if err := conf.UnmarshalStrict(options, &opts); err != nil {
 		p.Errf("cannot unmarshal configuration options: %s", err)
 	}
 
-	if p.options.Timeout == 0 {
-		p.options.Timeout = global.Timeout
+	if err := opts.resolveProfiles(); err != nil {
+		p.Errf("cannot resolve session profiles: %s", err)
+	}
+
+	if opts.Timeout == 0 {
+		opts.Timeout = global.Timeout
+	}
+
+	prev := p.options.Swap(&opts)
+
+	// A running plugin is being reconfigured, connections with outdated settings must not be used anymore.
+	if p.connMgr != nil && prev != nil {
+		p.reload(prev, &opts)
+	}
+}
+
+// reload applies new options to the running plugin. Only connections of sessions whose effective
+// settings changed are closed, requests in progress finish on their old connections.
+// The exporter is restarted if its address changed. Changes of Timeout and KeepAlive take effect after a restart.
+func (p *Plugin) reload(prev, opts *PluginOptions) {
+	if prev.Exporter.Listen != opts.Exporter.Listen {
+		p.stopExporter()
+		p.startExporter()
+	}
+
+	outdated := outdatedConnKeys(prev, opts)
+	if len(outdated) == 0 {
+		return
+	}
+
+	p.Infof("configuration reloaded, closing %d connection(s) with changed settings", len(outdated))
+	p.connMgr.Retire(outdated)
+}
+
+// outdatedConnKeys returns the keys of connections of the Default and named sessions made with the previous
+// options that would be made with different settings now, including sessions that were removed.
+func outdatedConnKeys(prev, opts *PluginOptions) map[connKey]bool {
+	keys := make(map[connKey]bool)
+
+	names := []string{""}
+	for name := range prev.Sessions {
+		names = append(names, name)
+	}
+
+	for _, name := range names {
+		prevKey, err := sessionConnKey(name, prev)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := opts.Sessions[name]; ok || name == "" {
+			key, err := sessionConnKey(name, opts)
+			if err == nil && key == prevKey {
+				continue
+			}
+		}
+
+		keys[prevKey] = true
+	}
+
+	return keys
+}
+
+// sessionConnKey resolves the session the same way Export does and returns the key of its connection.
+// An empty name stands for the Default session.
+func sessionConnKey(name string, opts *PluginOptions) (connKey, error) {
+	connURI, params, err := sessionParams(name, opts)
+	if err != nil {
+		return connKey{}, err
+	}
+
+	return createConnKey(*connURI, params), nil
+}
+
+// sessionParams returns the URI and connection parameters of the Default or a named session.
+func sessionParams(name string, opts *PluginOptions) (*uri.URI, map[string]string, error) {
+	params, err := sessionConnParams(name, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	connURI, err := uri.NewWithCreds(params[uriParam], params["User"], params["Password"], handlers.UriDefaults)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return connURI, params, nil
+}
+
+// sessionConnParams returns the connection parameters of the Default or a named session,
+// the options a named session does not set are taken from Default.
+func sessionConnParams(name string, opts *PluginOptions) (map[string]string, error) {
+	var rawParams []string
+	if name != "" {
+		rawParams = []string{name}
+	}
+
+	params, _, hc, err := metrics[keyPing].EvalParams(rawParams, opts.Sessions)
+	if err != nil {
+		return nil, err
+	}
+
+	err = metric.SetDefaults(params, hc, opts.Default)
+	if err != nil {
+		return nil, err
 	}
+
+	return params, nil
 }
 
 // Validate implements the Configurator interface.
@@ -78,15 +242,273 @@ func (*Plugin) Validate(options any) error {
 		return err
 	}
 
-	for _, s := range opts.Sessions {
-		if !contains(validTLSOptions, s.TLSConnect) {
-			return fmt.Errorf("incorrect tls connection type %s", s.TLSConnect)
+	err = opts.resolveProfiles()
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(opts.Sessions))
+	for name := range opts.Sessions {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	err = opts.validateSession("")
+	if err != nil {
+		return fmt.Errorf("invalid default session: %w", err)
+	}
+
+	for _, name := range names {
+		err = opts.validateSession(name)
+		if err != nil {
+			return fmt.Errorf("invalid session %s: %w", name, err)
+		}
+	}
+
+	if !contains(handlers.JSONModes, opts.jsonMode()) {
+		return fmt.Errorf("unsupported JSON mode %s", opts.JSONMode)
+	}
+
+	err = opts.Exporter.validate(opts.Sessions)
+	if err != nil {
+		return fmt.Errorf("invalid exporter options: %w", err)
+	}
+
+	err = validateDir(opts.CustomQueriesPath)
+	if err != nil {
+		return fmt.Errorf("invalid custom queries path: %w", err)
+	}
+
+	err = validateDir(opts.BaselinesPath)
+	if err != nil {
+		return fmt.Errorf("invalid baselines path: %w", err)
+	}
+
+	return nil
+}
+
+// validateDir checks that an optional directory option refers to an existing directory.
+func validateDir(path string) error {
+	if path == "" {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+
+	return nil
+}
+
+// resolveProfiles fills the options of the Default and named sessions that are not set with the values of
+// the profiles they refer to, so that metric parameters are looked up in the resolved sessions.
+func (o *PluginOptions) resolveProfiles() error {
+	for name, p := range o.Profiles {
+		if p.Profile != "" {
+			return fmt.Errorf("profile %s cannot refer to another profile", name)
+		}
+	}
+
+	for name, s := range o.Sessions {
+		err := s.inherit(o.Profiles)
+		if err != nil {
+			return fmt.Errorf("invalid session %s: %w", name, err)
+		}
+
+		o.Sessions[name] = s
+	}
+
+	err := o.Default.inherit(o.Profiles)
+	if err != nil {
+		return fmt.Errorf("invalid default session: %w", err)
+	}
+
+	return nil
+}
+
+// inherit copies the options of the session profile to the options that are not set in the session.
+func (s *Session) inherit(profiles map[string]Session) error {
+	if s.Profile == "" {
+		return nil
+	}
+
+	profile, ok := profiles[s.Profile]
+	if !ok {
+		return fmt.Errorf("unknown profile %s", s.Profile)
+	}
+
+	sv := reflect.ValueOf(s).Elem()
+	pv := reflect.ValueOf(profile)
+
+	for i := 0; i < sv.NumField(); i++ {
+		if sv.Field(i).String() == "" {
+			sv.Field(i).SetString(pv.Field(i).String())
+		}
+	}
+
+	return nil
+}
+
+// validateSession checks the URI and the TLS settings of the Default (an empty name) or a named session
+// the same way a connection would be created, so that misconfigurations are reported when the agent starts.
+// Named sessions are checked with the options they take from Default, while Default may hold shared TLS
+// options without enabling TLS itself.
+func (o *PluginOptions) validateSession(name string) error {
+	s := o.Default
+	if name != "" {
+		s = o.Sessions[name]
+	}
+
+	if s.URI != "" {
+		validator := uri.URIValidator{Defaults: handlers.UriDefaults, AllowedSchemes: []string{"tcp"}}
+
+		err := validator.Validate(&s.URI)
+		if err != nil {
+			return fmt.Errorf("invalid URI: %w", err)
+		}
+	}
+
+	params, err := sessionConnParams(name, o)
+	if err != nil {
+		return err
+	}
+
+	if !contains(validTLSOptions, params[tlsConnectParam]) {
+		return fmt.Errorf("incorrect tls connection type %s", params[tlsConnectParam])
+	}
+
+	if name != "" && params[tlsConnectParam] == "" && hasTLSOptions(params) {
+		return errors.New("TLS options are set, but TLSConnect is not")
+	}
+
+	tlsOpts, err := tlsOptionsFromParams(params)
+	if err != nil {
+		return err
+	}
+
+	details, err := createTLS(params)
+	if err != nil {
+		return err
+	}
+
+	if details.TlsCaFile != "" {
+		_, err = loadCertPool(details.TlsCaFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if details.TlsCertFile != "" || details.TlsKeyFile != "" {
+		_, err = tlsOpts.loadCertificates(details)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasTLSOptions reports whether any TLS option other than TLSConnect is set in the connection parameters.
+func hasTLSOptions(params map[string]string) bool {
+	for _, name := range []string{
+		tlsCAParam, tlsCertParam, tlsKeyParam, tlsKeyPasswordParam,
+		tlsMinVersionParam, tlsCipherSuitesParam, tlsServerNameParam, tlsCRLParam,
+	} {
+		if params[name] != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *ExporterOptions) validate(sessions map[string]Session) error {
+	if e.Listen != "" {
+		_, _, err := net.SplitHostPort(e.Listen)
+		if err != nil {
+			return fmt.Errorf("invalid listen address: %w", err)
+		}
+	}
+
+	for _, name := range splitList(e.Sessions) {
+		if _, ok := sessions[name]; !ok {
+			return fmt.Errorf("unknown session %s", name)
 		}
 	}
 
 	return nil
 }
 
+// exporterSessions returns the names of sessions scraped by the exporter, the Default session
+// (an empty name) is scraped if there are no named sessions.
+func (o *PluginOptions) exporterSessions() []string {
+	names := splitList(o.Exporter.Sessions)
+	if len(names) > 0 {
+		return names
+	}
+
+	for name := range o.Sessions {
+		names = append(names, name)
+	}
+
+	if len(names) == 0 {
+		return []string{""}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// handlersConfig returns the settings passed to handlers with every request.
+func (o *PluginOptions) handlersConfig() *handlers.Config {
+	return &handlers.Config{
+		CustomQueriesPath: o.CustomQueriesPath,
+		BaselinesPath:     o.BaselinesPath,
+		AllowedCommands:   o.allowedCommands(),
+		JSONMode:          o.jsonMode(),
+	}
+}
+
+// allowedCommands returns the configured command allowlist or the default one.
+func (o *PluginOptions) allowedCommands() []string {
+	list := splitList(o.AllowedCommands)
+	if len(list) == 0 {
+		return handlers.DefaultAllowedCommands
+	}
+
+	return list
+}
+
+// jsonMode returns the configured JSON mode, legacy if it is not set.
+func (o *PluginOptions) jsonMode() string {
+	if o.JSONMode == "" {
+		return handlers.JSONModeLegacy
+	}
+
+	return o.JSONMode
+}
+
+// splitList splits a comma separated option value, ignoring empty items.
+func splitList(value string) []string {
+	var list []string
+
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			list = append(list, v)
+		}
+	}
+
+	return list
+}
+
 func contains(s []string, e string) bool {
 	for _, v := range s {
 		if v == e {
@@ -0,0 +1,460 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.zabbix.com/plugin/mongodb/plugin/internal/tlstest"
+	"golang.zabbix.com/sdk/conf"
+	"golang.zabbix.com/sdk/plugin"
+)
+
+func TestPlugin_Validate(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := tlstest.NewCA(t, "Test CA")
+
+	caFile := tlstest.WriteFile(t, dir, "ca.crt", ca.PEM())
+	issue := func(serial int64) tls.Certificate {
+		return ca.Issue(t, serial, "agent", time.Now().Add(time.Hour), x509.ExtKeyUsageClientAuth)
+	}
+
+	certFile, keyFile := tlstest.WriteKeyPair(t, dir, "client", issue(2), "")
+	otherCert, _ := tlstest.WriteKeyPair(t, dir, "other", issue(3), "")
+	encCert, encKey := tlstest.WriteKeyPair(t, dir, "enc", issue(4), "secret")
+	notPEM := tlstest.WriteFile(t, dir, "bad.crt", []byte("not a certificate"))
+	missing := filepath.Join(dir, "missing.crt")
+
+	tests := []struct {
+		name    string
+		config  []string
+		wantErr string
+	}{
+		{"+empty", nil, ""},
+		{
+			"+fullSession",
+			[]string{
+				"Sessions.Prod.Uri=tcp://mongo.example.com:27017",
+				"Sessions.Prod.TLSConnect=verify_full",
+				"Sessions.Prod.TLSCAFile=" + caFile,
+				"Sessions.Prod.TLSCertFile=" + certFile,
+				"Sessions.Prod.TLSKeyFile=" + keyFile,
+				"Sessions.Prod.TLSMinVersion=1.2",
+			},
+			"",
+		},
+		{
+			"+encryptedKey",
+			[]string{
+				"Default.TLSConnect=required",
+				"Default.TLSCertFile=" + encCert,
+				"Default.TLSKeyFile=" + encKey,
+				"Default.TLSKeyPassword=secret",
+			},
+			"",
+		},
+		{"-tlsConnect", []string{"Sessions.Prod.TLSConnect=always"}, "invalid session Prod"},
+		{"-defaultTLSConnect", []string{"Default.TLSConnect=always"}, "invalid default session"},
+		{"-uriScheme", []string{"Sessions.Prod.Uri=http://localhost"}, "invalid session Prod: invalid URI"},
+		{"-defaultURI", []string{"Default.Uri=tcp://localhost:99999"}, "invalid default session: invalid URI"},
+		{
+			"-missingCAFile",
+			[]string{"Sessions.Prod.TLSConnect=verify_ca"},
+			"invalid session Prod",
+		},
+		{
+			"-unreadableCAFile",
+			[]string{"Sessions.Prod.TLSConnect=verify_ca", "Sessions.Prod.TLSCAFile=" + missing},
+			"Failed to read TLS CA file",
+		},
+		{
+			"-invalidCAFile",
+			[]string{"Sessions.Prod.TLSConnect=verify_ca", "Sessions.Prod.TLSCAFile=" + notPEM},
+			"No certificates found in TLS CA file",
+		},
+		{
+			"-keyWithoutCert",
+			[]string{"Sessions.Prod.TLSConnect=required", "Sessions.Prod.TLSKeyFile=" + keyFile},
+			"invalid session Prod",
+		},
+		{
+			"-certKeyMismatch",
+			[]string{
+				"Sessions.Prod.TLSConnect=required",
+				"Sessions.Prod.TLSCertFile=" + otherCert,
+				"Sessions.Prod.TLSKeyFile=" + keyFile,
+			},
+			"private key does not match public key",
+		},
+		{
+			"-encryptedKeyWrongPassword",
+			[]string{
+				"Sessions.Prod.TLSConnect=required",
+				"Sessions.Prod.TLSCertFile=" + encCert,
+				"Sessions.Prod.TLSKeyFile=" + encKey,
+				"Sessions.Prod.TLSKeyPassword=wrong",
+			},
+			"Failed to decrypt TLS key file",
+		},
+		{
+			"-minVersion",
+			[]string{"Sessions.Prod.TLSConnect=required", "Sessions.Prod.TLSMinVersion=1.4"},
+			"unsupported TLS version",
+		},
+		{
+			"-tlsOptionsWithoutTLSConnect",
+			[]string{"Sessions.Prod.TLSCAFile=" + caFile},
+			"invalid session Prod: TLS options are set, but TLSConnect is not",
+		},
+		{
+			"+tlsFilesFromDefault",
+			[]string{
+				"Default.TLSCAFile=" + caFile,
+				"Default.TLSCertFile=" + certFile,
+				"Default.TLSKeyFile=" + keyFile,
+				"Sessions.Prod.TLSConnect=verify_ca",
+			},
+			"",
+		},
+		{
+			"-tlsFilesFromDefaultWithoutTLSConnect",
+			[]string{"Default.TLSCAFile=" + caFile, "Sessions.Prod.Uri=tcp://localhost"},
+			"invalid session Prod: TLS options are set, but TLSConnect is not",
+		},
+		{
+			"-unreadableDefaultCAFile",
+			[]string{"Default.TLSCAFile=" + missing, "Sessions.Prod.TLSConnect=verify_ca"},
+			"Failed to read TLS CA file",
+		},
+		{"+defaultTLSOptionsWithoutTLSConnect", []string{"Default.TLSServerName=mongo.example.com"}, ""},
+		{
+			"+exporter",
+			[]string{"Exporter.Listen=:9216", "Exporter.Sessions=Prod", "Sessions.Prod.Uri=tcp://localhost"},
+			"",
+		},
+		{"-exporterListen", []string{"Exporter.Listen=9216"}, "invalid exporter options: invalid listen address"},
+		{"+jsonMode", []string{"JSONMode=zabbix"}, ""},
+		{"-jsonMode", []string{"JSONMode=bson"}, "unsupported JSON mode bson"},
+		{"-exporterSession", []string{"Exporter.Sessions=Prod"}, "invalid exporter options: unknown session Prod"},
+		{"+baselinesPath", []string{"BaselinesPath=" + dir}, ""},
+		{"-baselinesPathNotDir", []string{"BaselinesPath=" + caFile}, "invalid baselines path"},
+		{"-baselinesPathMissing", []string{"BaselinesPath=" + missing}, "invalid baselines path"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := (&Plugin{}).Validate([]byte(strings.Join(tt.config, "\n")))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() error = %v", err)
+				}
+
+				return
+			}
+
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPluginOptions_resolveProfiles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		config  []string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			"+inherit",
+			[]string{
+				"Profiles.Cluster.User=zabbix",
+				"Profiles.Cluster.Password=secret",
+				"Profiles.Cluster.TLSConnect=verify_full",
+				"Profiles.Cluster.TLSCAFile=/etc/ssl/ca.crt",
+				"Sessions.Prod.Uri=tcp://mongo1:27017",
+				"Sessions.Prod.Profile=Cluster",
+			},
+			map[string]string{
+				uriParam:        "tcp://mongo1:27017",
+				"User":          "zabbix",
+				"Password":      "secret",
+				tlsConnectParam: "verify_full",
+				tlsCAParam:      "/etc/ssl/ca.crt",
+			},
+			"",
+		},
+		{
+			"+override",
+			[]string{
+				"Profiles.Cluster.User=zabbix",
+				"Profiles.Cluster.TLSConnect=verify_full",
+				"Sessions.Prod.Uri=tcp://mongo1:27017",
+				"Sessions.Prod.User=admin",
+				"Sessions.Prod.Profile=Cluster",
+			},
+			map[string]string{uriParam: "tcp://mongo1:27017", "User": "admin", tlsConnectParam: "verify_full"},
+			"",
+		},
+		{
+			"-unknownProfile",
+			[]string{"Sessions.Prod.Uri=tcp://mongo1:27017", "Sessions.Prod.Profile=Cluster"},
+			nil,
+			"invalid session Prod: unknown profile Cluster",
+		},
+		{
+			"-defaultUnknownProfile",
+			[]string{"Default.Profile=Cluster"},
+			nil,
+			"invalid default session: unknown profile Cluster",
+		},
+		{
+			"-nestedProfile",
+			[]string{"Profiles.Base.User=zabbix", "Profiles.Cluster.Profile=Base"},
+			nil,
+			"profile Cluster cannot refer to another profile",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var opts PluginOptions
+
+			err := conf.UnmarshalStrict([]byte(strings.Join(tt.config, "\n")), &opts)
+			if err != nil {
+				t.Fatalf("failed to unmarshal config: %v", err)
+			}
+
+			err = opts.resolveProfiles()
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("resolveProfiles() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("resolveProfiles() error = %v", err)
+			}
+
+			params, _, _, err := metrics[keyPing].EvalParams([]string{"Prod"}, opts.Sessions)
+			if err != nil {
+				t.Fatalf("EvalParams() error = %v", err)
+			}
+
+			for k, v := range tt.want {
+				if params[k] != v {
+					t.Errorf("EvalParams() %s = %q, want %q", k, params[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestOutdatedConnKeys(t *testing.T) {
+	t.Parallel()
+
+	base := []string{
+		"Default.User=zabbix",
+		"Profiles.Cluster.Password=old",
+		"Sessions.A.Uri=tcp://mongo1:27017",
+		"Sessions.A.Profile=Cluster",
+		"Sessions.B.Uri=tcp://mongo2:27017",
+		"Sessions.B.Password=secret",
+		"Sessions.C.Uri=tcp://mongo3:27017",
+	}
+
+	tests := []struct {
+		name   string
+		config []string
+		want   []string
+	}{
+		{"+unchanged", base, nil},
+		{"+profileRotated", replaceLine(base, "Profiles.Cluster.Password=new"), []string{"A"}},
+		{"+sameEffectiveSettings", append(base, "Sessions.B.Profile=Cluster"), nil},
+		{"+removed", base[:len(base)-1], []string{"C"}},
+		{"+added", append(base, "Sessions.D.Uri=tcp://mongo4:27017"), nil},
+		{"+defaultChanged", replaceLine(base, "Default.User=monitor"), []string{"", "A", "B", "C"}},
+	}
+
+	prev := parseOptions(t, base)
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := outdatedConnKeys(prev, parseOptions(t, tt.config))
+
+			want := make(map[connKey]bool)
+			for _, name := range tt.want {
+				key, err := sessionConnKey(name, prev)
+				if err != nil {
+					t.Fatalf("sessionConnKey() error = %v", err)
+				}
+
+				want[key] = true
+			}
+
+			if len(got) != len(want) {
+				t.Fatalf("outdatedConnKeys() = %d keys, want %d", len(got), len(want))
+			}
+
+			for key := range want {
+				if !got[key] {
+					t.Errorf("outdatedConnKeys() is missing %s", key.rawUri)
+				}
+			}
+		})
+	}
+}
+
+func TestPlugin_Configure_concurrentExport(t *testing.T) {
+	t.Parallel()
+
+	p := &Plugin{stats: newRequestStats()}
+	p.Logger = &cliLogger{out: io.Discard}
+	p.Configure(&plugin.GlobalOptions{Timeout: 1}, []byte("Sessions.A.Password=old\nJSONMode=zabbix"))
+	p.connMgr = NewConnManager(time.Minute, time.Second, hkInterval*time.Second, p.Logger)
+
+	defer p.connMgr.Destroy()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				_, err := p.Export(keyPluginStats, []string{formatJSON}, &cliContext{timeout: 1})
+				if err != nil {
+					t.Errorf("Export() error = %v", err)
+
+					return
+				}
+
+				if mode := p.config().handlersConfig().JSONMode; mode != "zabbix" && mode != "relaxed" {
+					t.Errorf("handlersConfig() JSON mode = %s", mode)
+
+					return
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < 100; i++ {
+		p.Configure(&plugin.GlobalOptions{Timeout: 1}, []byte("Sessions.A.Password=new\nJSONMode=relaxed"))
+	}
+
+	wg.Wait()
+}
+
+func parseOptions(t *testing.T, config []string) *PluginOptions {
+	t.Helper()
+
+	var opts PluginOptions
+
+	err := conf.UnmarshalStrict([]byte(strings.Join(config, "\n")), &opts)
+	if err != nil {
+		t.Fatalf("failed to unmarshal config: %v", err)
+	}
+
+	err = opts.resolveProfiles()
+	if err != nil {
+		t.Fatalf("failed to resolve profiles: %v", err)
+	}
+
+	return &opts
+}
+
+// replaceLine returns a copy of the config with the line setting the same option replaced.
+func replaceLine(config []string, line string) []string {
+	option := strings.SplitN(line, "=", 2)[0]
+	res := make([]string, 0, len(config))
+
+	for _, l := range config {
+		if strings.HasPrefix(l, option+"=") {
+			l = line
+		}
+
+		res = append(res, l)
+	}
+
+	return res
+}
+
+func TestPlugin_Configure_exporterListen(t *testing.T) {
+	t.Parallel()
+
+	global := &plugin.GlobalOptions{Timeout: 1}
+
+	p := &Plugin{}
+	p.Logger = &cliLogger{out: io.Discard}
+	p.Configure(global, []byte("Exporter.Listen=127.0.0.1:0"))
+	p.startConnManager()
+
+	if p.exporter != nil {
+		t.Fatalf("startConnManager() started the exporter")
+	}
+
+	p.Stop()
+	p.Start()
+
+	defer p.Stop()
+
+	first := p.exporter
+	if first == nil {
+		t.Fatalf("Start() did not start the exporter")
+	}
+
+	p.Configure(global, []byte("Exporter.Listen=127.0.0.1:0\nSessions.A.Uri=tcp://localhost"))
+
+	if p.exporter != first {
+		t.Fatalf("Configure() restarted the exporter, but its address did not change")
+	}
+
+	p.Configure(global, []byte("Exporter.Listen=localhost:0"))
+
+	if p.exporter == nil || p.exporter == first {
+		t.Fatalf("Configure() did not restart the exporter on a new address")
+	}
+
+	p.Configure(global, []byte(""))
+
+	if p.exporter != nil {
+		t.Fatalf("Configure() did not stop the disabled exporter")
+	}
+}
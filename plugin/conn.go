@@ -18,6 +18,7 @@ import (
 	"context"
 	"crypto/tls"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -45,6 +46,17 @@ type MongoConn struct {
 	timeout        time.Duration
 	lastTimeAccess time.Time
 	session        mongo.Session
+	state          *handlers.State
+	peer           func(ctx context.Context, host string) (*MongoConn, func(), error)
+
+	// origin is the key of the session connection the connection was created for,
+	// peer connections are retired together with it.
+	origin connKey
+
+	// inUse is the number of requests running on the connection, a retired connection
+	// is closed when the last of them is released.
+	inUse   int
+	retired bool
 }
 
 // MongoDatabase wraps a mgo.Database to embed methods in models.
@@ -74,6 +86,14 @@ type ConnManager struct {
 	timeout       time.Duration
 	Destroy       context.CancelFunc
 	log           log.Logger
+
+	// Counters reported by the mongodb.plugin.stats key.
+	created         atomic.Int64
+	closed          atomic.Int64
+	failed          atomic.Int64
+	hits            atomic.Int64
+	misses          atomic.Int64
+	housekeeperRuns atomic.Int64
 }
 
 type connKey struct {
@@ -83,6 +103,11 @@ type connKey struct {
 	tlsCA      string
 	tlsCert    string
 	tlsKey     string
+	tlsMinVer  string
+	tlsCiphers string
+	tlsServer  string
+	tlsKeyPass string
+	tlsCRL     string
 }
 
 // DB shadows *mgo.DB to returns a Database interface instead of *mgo.Database.
@@ -127,6 +152,23 @@ func (conn *MongoConn) Ping(ctx context.Context) error {
 	return nil
 }
 
+// State returns values kept by handlers between polls of this connection.
+func (conn *MongoConn) State() *handlers.State {
+	return conn.state
+}
+
+// Peer returns a connection to another member of the deployment using the same credentials and TLS settings.
+// The context limits the time to connect to the member. The connection is held by the request until the returned
+// function is called, so it is not closed meanwhile.
+func (conn *MongoConn) Peer(ctx context.Context, host string) (handlers.Session, func(), error) { //nolint:ireturn
+	peer, release, err := conn.peer(ctx, host)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return peer, release, nil
+}
+
 func (conn *MongoConn) getTimeout() time.Duration {
 	return conn.timeout
 }
@@ -136,10 +178,55 @@ func (d *MongoDatabase) C(name string) handlers.Collection {
 	return &MongoCollection{Collection: d.Collection(name)}
 }
 
-// CollectionNames lists collection names in a DB.
+// CollectionNames lists names of the collections in a DB the user is authorized to access.
+// Listing only names does not lock the collections and does not require the listCollections privilege.
 func (d *MongoDatabase) CollectionNames(ctx context.Context) ([]string, error) {
-	//nolint:wrapcheck
-	return d.Database.ListCollectionNames(ctx, bson.D{})
+	cursor, err := d.Database.RunCommandCursor(ctx, bson.D{
+		{Key: "listCollections", Value: 1},
+		{Key: "nameOnly", Value: true},
+		{Key: "authorizedCollections", Value: true},
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to list collections")
+	}
+
+	var specs []struct {
+		Name string `bson:"name"`
+	}
+
+	err = cursor.All(ctx, &specs)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to read collections")
+	}
+
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+
+	return names, nil
+}
+
+// CollectionSpecs lists the collections in a DB with their type and options. Only collections the user is
+// authorized to access are requested, the server refuses the command as unauthorized if the user is not allowed
+// to list the options.
+func (d *MongoDatabase) CollectionSpecs(ctx context.Context) ([]handlers.CollectionSpec, error) {
+	cursor, err := d.Database.RunCommandCursor(ctx, bson.D{
+		{Key: "listCollections", Value: 1},
+		{Key: "authorizedCollections", Value: true},
+	})
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to list collections")
+	}
+
+	var specs []handlers.CollectionSpec
+
+	err = cursor.All(ctx, &specs)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to read collections")
+	}
+
+	return specs, nil
 }
 
 // Run shadows *mgo.DB to returns a Database interface instead of *mgo.Database.
@@ -150,6 +237,20 @@ func (d *MongoDatabase) Run(ctx context.Context, cmd, result any) error {
 
 // Collection is an interface to access to the collection struct.
 
+// Aggregate shadows *mongo.Collection to returns a Query interface instead of *mongo.Cursor.
+func (c *MongoCollection) Aggregate( //nolint:ireturn
+	ctx context.Context,
+	pipeline any,
+	opts ...*options.AggregateOptions,
+) (handlers.Query, error) {
+	cursor, err := c.Collection.Aggregate(ctx, pipeline, opts...)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to execute aggregation")
+	}
+
+	return &MongoQuery{Cursor: cursor}, nil
+}
+
 // Find shadows *mgo.Collection to returns a Query interface instead of *mgo.Query.
 func (c *MongoCollection) Find( //nolint:ireturn
 	ctx context.Context,
@@ -229,27 +330,114 @@ func NewConnManager(
 }
 
 // GetConnection returns an existing connection or creates a new one.
+// The connection must be given back with Release when the request is done.
 func (c *ConnManager) GetConnection(
 	connURI uri.URI, //nolint:gocritic
 	params map[string]string,
 ) (*MongoConn, error) {
 	ck := createConnKey(connURI, params)
 
+	return c.getConnection(context.Background(), ck, ck, params)
+}
+
+// getConnection returns an existing connection or creates a new one on behalf of the origin session connection.
+// The context limits the time to check a new connection.
+func (c *ConnManager) getConnection(
+	ctx context.Context,
+	ck, origin connKey, //nolint:gocritic
+	params map[string]string,
+) (*MongoConn, error) {
 	conn := c.getConn(ck)
 	if conn != nil {
-		c.log.Tracef("connection found for host: %s", connURI.Host())
+		c.log.Tracef("connection found for host: %s", ck.uri.Host())
+		c.hits.Add(1)
 
 		return conn, nil
 	}
 
-	conn, err := c.create(ck, params)
+	c.misses.Add(1)
+
+	conn, err := c.create(ctx, ck, origin, params)
 	if err != nil {
+		c.failed.Add(1)
+
 		return nil, errs.Wrap(err, "failed to create new connection")
 	}
 
+	c.created.Add(1)
+
 	return c.setConn(ck, conn), nil
 }
 
+// Release marks the end of a request on the connection and closes it if it was retired meanwhile.
+func (c *ConnManager) Release(conn *MongoConn) {
+	c.connectionsMu.Lock()
+	defer c.connectionsMu.Unlock()
+
+	conn.inUse--
+
+	if conn.retired && conn.inUse == 0 {
+		c.closeConn(conn)
+	}
+}
+
+// Retire removes connections matching the given keys and the peer connections created for them,
+// so that the next requests create new ones.
+// Connections with requests in progress are closed when the last of them is released.
+func (c *ConnManager) Retire(keys map[connKey]bool) {
+	c.connectionsMu.Lock()
+	defer c.connectionsMu.Unlock()
+
+	for ck, conn := range c.connections {
+		if !keys[ck] && !keys[conn.origin] {
+			continue
+		}
+
+		delete(c.connections, ck)
+		conn.retired = true
+
+		if conn.inUse == 0 {
+			c.closeConn(conn)
+		}
+	}
+}
+
+func (c *ConnManager) closeConn(conn *MongoConn) {
+	c.closed.Add(1)
+
+	err := closeSession(context.Background(), conn.session)
+	if err != nil {
+		c.log.Warningf("retired session client clean-up failed: %s", err.Error())
+	}
+
+	c.log.Debugf("Closed retired connection: %s", conn.addr)
+}
+
+// getPeerConnection returns a connection to the host with the credentials and TLS settings of the original one.
+func (c *ConnManager) getPeerConnection(
+	ctx context.Context,
+	origin connKey, //nolint:gocritic
+	host string,
+	params map[string]string,
+) (*MongoConn, error) {
+	connURI := origin.uri
+	rawURI := connURI.Scheme() + "://" + host
+
+	peerURI, err := uri.NewWithCreds(rawURI, connURI.User(), connURI.Password(), handlers.UriDefaults)
+	if err != nil {
+		return nil, errs.Wrapf(err, "failed to parse peer address %s", host)
+	}
+
+	peerParams := make(map[string]string, len(params))
+	for k, v := range params {
+		peerParams[k] = v
+	}
+
+	peerParams[uriParam] = rawURI
+
+	return c.getConnection(ctx, createConnKey(*peerURI, peerParams), origin, peerParams)
+}
+
 // getConn returns a connection with given uri if it exists and also updates
 // lastTimeAccess, otherwise returns nil.
 func (c *ConnManager) getConn(ck connKey) *MongoConn { //nolint:gocritic
@@ -262,6 +450,7 @@ func (c *ConnManager) getConn(ck connKey) *MongoConn { //nolint:gocritic
 	}
 
 	conn.updateAccessTime()
+	conn.inUse++
 
 	return conn
 }
@@ -275,6 +464,8 @@ func (c *ConnManager) setConn(
 
 	existingConn, ok := c.connections[ck]
 	if ok {
+		c.closed.Add(1)
+
 		err := closeSession(context.Background(), conn.session)
 		if err != nil {
 			c.log.Warningf("set conn session client clean-up failed: %s", err.Error())
@@ -282,21 +473,27 @@ func (c *ConnManager) setConn(
 
 		c.log.Debugf("Closed unused connection: %s", ck.uri.Addr())
 
+		existingConn.inUse++
+
 		return existingConn
 	}
 
 	c.connections[ck] = conn
+	conn.inUse++
 
 	return conn
 }
 
 // closeUnused closes each connection that has not been accessed at least within the keepalive interval.
+// Connections with requests in progress are kept.
 func (c *ConnManager) closeUnused() {
 	c.connectionsMu.Lock()
 	defer c.connectionsMu.Unlock()
 
 	for ck, conn := range c.connections {
-		if time.Since(conn.lastTimeAccess) > c.keepAlive {
+		if conn.inUse == 0 && time.Since(conn.lastTimeAccess) > c.keepAlive {
+			c.closed.Add(1)
+
 			err := closeSession(context.Background(), conn.session)
 			if err != nil {
 				c.log.Warningf("unused session client clean-up failed: %s", err.Error())
@@ -312,6 +509,8 @@ func (c *ConnManager) closeUnused() {
 func (c *ConnManager) closeAll() {
 	c.connectionsMu.Lock()
 	for uri, conn := range c.connections {
+		c.closed.Add(1)
+
 		err := closeSession(context.Background(), conn.session)
 		if err != nil {
 			c.log.Warningf("close all session client clean-up failed: %s", err.Error())
@@ -335,14 +534,16 @@ func (c *ConnManager) housekeeper(ctx context.Context, interval time.Duration) {
 
 			return
 		case <-ticker.C:
+			c.housekeeperRuns.Add(1)
 			c.closeUnused()
 		}
 	}
 }
 
-// create creates a new connection with given credentials.
+// create creates a new connection with given credentials, the context limits the time to check it.
 func (c *ConnManager) create(
-	ck connKey, //nolint:gocritic
+	ctx context.Context,
+	ck, origin connKey, //nolint:gocritic
 	params map[string]string,
 ) (*MongoConn, error) {
 	opt, err := c.createOptions(ck.uri, params)
@@ -372,7 +573,7 @@ func (c *ConnManager) create(
 		return nil, err
 	}
 
-	err = session.Client().Ping(context.Background(), readpref.Nearest())
+	err = session.Client().Ping(ctx, readpref.Nearest())
 	if err != nil {
 		c.log.Debugf("session client ping failed: %s", ck.uri.Addr())
 
@@ -391,6 +592,16 @@ func (c *ConnManager) create(
 		timeout:        c.timeout,
 		lastTimeAccess: time.Now(),
 		session:        session,
+		state:          handlers.NewState(),
+		origin:         origin,
+		peer: func(ctx context.Context, host string) (*MongoConn, func(), error) {
+			peer, err := c.getPeerConnection(ctx, origin, host, params)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return peer, func() { c.Release(peer) }, nil
+		},
 	}, nil
 }
 
@@ -415,7 +626,12 @@ func (c *ConnManager) createOptions(
 	}
 
 	if details.TlsConnect != disable {
-		err := c.setTLSConfig(opt, details)
+		tlsOpts, err := tlsOptionsFromParams(params)
+		if err != nil {
+			return nil, zbxerr.ErrorInvalidConfiguration.Wrap(err)
+		}
+
+		err = c.setTLSConfig(opt, details, tlsOpts)
 		if err != nil {
 			return nil, err
 		}
@@ -473,18 +689,23 @@ func createTLS(params map[string]string) (*tlsconfig.Details, error) {
 func (c *ConnManager) setTLSConfig(
 	opt *options.ClientOptions,
 	details *tlsconfig.Details,
+	tlsOpts *tlsOptions,
 ) error {
 	var cfg *tls.Config
 	var err error
 
+	// Client certificates are loaded separately, as the key may be encrypted.
+	noCerts := *details
+	noCerts.TlsCertFile, noCerts.TlsKeyFile = "", ""
+
 	switch details.TlsConnect {
 	case "required":
-		cfg, err = c.getRequiredTLSConfig(details)
+		cfg, err = c.getRequiredTLSConfig(&noCerts)
 		if err != nil {
 			return err
 		}
 	case "verify_ca":
-		cfg, err = details.GetTLSConfig(true)
+		cfg, err = noCerts.GetTLSConfig(true)
 		if err != nil {
 			return errs.Wrap(err, "failed to get TLS config for verify_ca connection")
 		}
@@ -494,12 +715,22 @@ func (c *ConnManager) setTLSConfig(
 			cfg.RootCAs,
 		)
 	case "verify_full":
-		cfg, err = details.GetTLSConfig(false)
+		cfg, err = noCerts.GetTLSConfig(false)
 		if err != nil {
 			return errs.Wrap(err, "failed to get TLS config for verify_full connection")
 		}
 	}
 
+	if cfg == nil {
+		return nil
+	}
+
+	cfg.Certificates, err = tlsOpts.loadCertificates(details)
+	if err != nil {
+		return err
+	}
+
+	tlsOpts.apply(cfg)
 	opt.SetTLSConfig(cfg)
 
 	return nil
@@ -560,5 +791,10 @@ func createConnKey(uri uri.URI, params map[string]string) connKey {
 		tlsCA:      params[tlsCAParam],
 		tlsCert:    params[tlsCertParam],
 		tlsKey:     params[tlsKeyParam],
+		tlsMinVer:  params[tlsMinVersionParam],
+		tlsCiphers: params[tlsCipherSuitesParam],
+		tlsServer:  params[tlsServerNameParam],
+		tlsKeyPass: params[tlsKeyPasswordParam],
+		tlsCRL:     params[tlsCRLParam],
 	}
 }
@@ -0,0 +1,140 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.zabbix.com/sdk/log"
+	"golang.zabbix.com/sdk/uri"
+)
+
+// newOfflineConn returns a connection with a session of a client that never reaches a server.
+func newOfflineConn(t *testing.T) *MongoConn {
+	t.Helper()
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://127.0.0.1:1"))
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+
+	session, err := client.StartSession()
+	if err != nil {
+		t.Fatalf("failed to start session: %v", err)
+	}
+
+	return &MongoConn{addr: "127.0.0.1:1", session: session}
+}
+
+func isDisconnected(conn *MongoConn) bool {
+	// A canceled context fails live clients at once, disconnected ones report it before that.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := conn.session.Client().ListDatabaseNames(ctx, struct{}{})
+
+	return errors.Is(err, mongo.ErrClientDisconnected)
+}
+
+func TestConnManager_Retire(t *testing.T) {
+	t.Parallel()
+
+	u, err := uri.New("tcp://127.0.0.1:1", nil)
+	if err != nil {
+		t.Fatalf("failed to parse uri: %v", err)
+	}
+
+	idleKey := createConnKey(*u, map[string]string{uriParam: "idle"})
+	busyKey := createConnKey(*u, map[string]string{uriParam: "busy"})
+	keptKey := createConnKey(*u, map[string]string{uriParam: "kept"})
+	peerKey := createConnKey(*u, map[string]string{uriParam: "peer"})
+
+	c := &ConnManager{connections: make(map[connKey]*MongoConn), log: log.New("test")}
+
+	idle := c.setConn(idleKey, newOfflineConn(t))
+	busy := c.setConn(busyKey, newOfflineConn(t))
+	kept := c.setConn(keptKey, newOfflineConn(t))
+
+	// A peer connection opened on behalf of the idle one.
+	peerConn := newOfflineConn(t)
+	peerConn.origin = idleKey
+	peer := c.setConn(peerKey, peerConn)
+
+	c.Release(idle)
+	c.Release(kept)
+	c.Release(peer)
+
+	c.Retire(map[connKey]bool{idleKey: true, busyKey: true})
+
+	if len(c.connections) != 1 || c.connections[keptKey] != kept {
+		t.Fatalf("connections = %v, want only the kept one", c.connections)
+	}
+
+	if !isDisconnected(idle) {
+		t.Errorf("idle retired connection is not closed")
+	}
+
+	if !isDisconnected(peer) {
+		t.Errorf("peer connection of a retired connection is not closed")
+	}
+
+	if isDisconnected(busy) || isDisconnected(kept) {
+		t.Fatalf("connection in use or not retired is closed")
+	}
+
+	c.Release(busy)
+
+	if !isDisconnected(busy) {
+		t.Errorf("busy retired connection is not closed after release")
+	}
+}
+
+func TestConnManager_closeUnused(t *testing.T) {
+	t.Parallel()
+
+	u, err := uri.New("tcp://127.0.0.1:1", nil)
+	if err != nil {
+		t.Fatalf("failed to parse uri: %v", err)
+	}
+
+	idleKey := createConnKey(*u, map[string]string{uriParam: "idle"})
+	busyKey := createConnKey(*u, map[string]string{uriParam: "busy"})
+
+	c := &ConnManager{connections: make(map[connKey]*MongoConn), log: log.New("test")}
+
+	idle := c.setConn(idleKey, newOfflineConn(t))
+	busy := c.setConn(busyKey, newOfflineConn(t))
+
+	c.Release(idle)
+
+	// Both connections are idle for longer than the keepalive interval, but a request still holds the busy one.
+	c.closeUnused()
+
+	if len(c.connections) != 1 || c.connections[busyKey] != busy {
+		t.Fatalf("connections = %v, want only the busy one", c.connections)
+	}
+
+	if !isDisconnected(idle) {
+		t.Errorf("unused connection is not closed")
+	}
+
+	if isDisconnected(busy) {
+		t.Errorf("connection in use is closed")
+	}
+}
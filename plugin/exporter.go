@@ -0,0 +1,374 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.zabbix.com/plugin/mongodb/plugin/handlers"
+)
+
+const (
+	defaultSessionLabel = "Default"
+	exporterPath        = "/metrics"
+	shutdownTimeout     = 5 * time.Second
+)
+
+type pathMetric struct {
+	path   string
+	name   string
+	typ    string
+	help   string
+	labels []string
+}
+
+var serverStatusMetrics = []pathMetric{
+	{"uptime", "mongodb_uptime_seconds", typeGauge, "Time since the server started.", nil},
+	{"connections.current", "mongodb_connections", typeGauge, "Incoming connections.", []string{"state", "current"}},
+	{
+		"connections.available", "mongodb_connections", typeGauge, "Incoming connections.",
+		[]string{"state", "available"},
+	},
+	{"connections.totalCreated", "mongodb_connections_created", typeCounter, "Incoming connections created.", nil},
+	{"opcounters.insert", "mongodb_op_counters", typeCounter, "Operations by type.", []string{"type", "insert"}},
+	{"opcounters.query", "mongodb_op_counters", typeCounter, "Operations by type.", []string{"type", "query"}},
+	{"opcounters.update", "mongodb_op_counters", typeCounter, "Operations by type.", []string{"type", "update"}},
+	{"opcounters.delete", "mongodb_op_counters", typeCounter, "Operations by type.", []string{"type", "delete"}},
+	{"opcounters.getmore", "mongodb_op_counters", typeCounter, "Operations by type.", []string{"type", "getmore"}},
+	{"opcounters.command", "mongodb_op_counters", typeCounter, "Operations by type.", []string{"type", "command"}},
+	{"mem.resident", "mongodb_memory_megabytes", typeGauge, "Memory in use.", []string{"type", "resident"}},
+	{"mem.virtual", "mongodb_memory_megabytes", typeGauge, "Memory in use.", []string{"type", "virtual"}},
+	{"network.bytesIn", "mongodb_network_bytes", typeCounter, "Network traffic.", []string{"direction", "in"}},
+	{"network.bytesOut", "mongodb_network_bytes", typeCounter, "Network traffic.", []string{"direction", "out"}},
+	{"network.numRequests", "mongodb_network_requests", typeCounter, "Requests received.", nil},
+	{"asserts.regular", "mongodb_asserts", typeCounter, "Assertions raised by type.", []string{"type", "regular"}},
+	{"asserts.warning", "mongodb_asserts", typeCounter, "Assertions raised by type.", []string{"type", "warning"}},
+	{"asserts.msg", "mongodb_asserts", typeCounter, "Assertions raised by type.", []string{"type", "msg"}},
+	{"asserts.user", "mongodb_asserts", typeCounter, "Assertions raised by type.", []string{"type", "user"}},
+}
+
+var connPoolMetrics = []pathMetric{
+	{
+		"totalInUse", "mongodb_connpool_connections", typeGauge, "Outgoing connections of the server pools.",
+		[]string{"state", "in_use"},
+	},
+	{
+		"totalAvailable", "mongodb_connpool_connections", typeGauge, "Outgoing connections of the server pools.",
+		[]string{"state", "available"},
+	},
+	{
+		"totalRefreshing", "mongodb_connpool_connections", typeGauge, "Outgoing connections of the server pools.",
+		[]string{"state", "refreshing"},
+	},
+	{"totalCreated", "mongodb_connpool_connections_created", typeCounter, "Outgoing connections created.", nil},
+}
+
+var dbStatsMetrics = []pathMetric{
+	{"collections", "mongodb_db_collections", typeGauge, "Collections in the database.", nil},
+	{"objects", "mongodb_db_objects", typeGauge, "Documents in the database.", nil},
+	{"dataSize", "mongodb_db_data_size_bytes", typeGauge, "Uncompressed size of the data.", nil},
+	{"storageSize", "mongodb_db_storage_size_bytes", typeGauge, "Storage allocated for the data.", nil},
+	{"indexes", "mongodb_db_indexes", typeGauge, "Indexes in the database.", nil},
+	{"indexSize", "mongodb_db_index_size_bytes", typeGauge, "Storage allocated for the indexes.", nil},
+}
+
+var collStatsMetrics = []pathMetric{
+	{"count", "mongodb_collection_documents", typeGauge, "Documents in the collection.", nil},
+	{"size", "mongodb_collection_size_bytes", typeGauge, "Uncompressed size of the data.", nil},
+	{"storageSize", "mongodb_collection_storage_size_bytes", typeGauge, "Storage allocated for the data.", nil},
+	{"nindexes", "mongodb_collection_indexes", typeGauge, "Indexes of the collection.", nil},
+	{"totalIndexSize", "mongodb_collection_index_size_bytes", typeGauge, "Storage allocated for the indexes.", nil},
+}
+
+var memberMetrics = []pathMetric{
+	{"lag", "mongodb_rs_member_lag_seconds", typeGauge, "Replication lag of the member behind the primary.", nil},
+	{"health", "mongodb_rs_member_health", typeGauge, "Health of the member, 1 if it is up.", nil},
+	{"state", "mongodb_rs_member_state", typeGauge, "Replica set state of the member.", nil},
+}
+
+// startExporter starts the OpenMetrics endpoint if it is configured.
+func (p *Plugin) startExporter() {
+	listen := p.config().Exporter.Listen
+	if listen == "" {
+		return
+	}
+
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		p.Errf("cannot start exporter: %s", err)
+
+		return
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(exporterPath, p.serveMetrics)
+
+	p.exporter = &http.Server{Handler: mux, ReadHeaderTimeout: shutdownTimeout}
+
+	go func(srv *http.Server) {
+		err := srv.Serve(ln)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			p.Errf("exporter stopped: %s", err)
+		}
+	}(p.exporter)
+
+	p.Infof("exporter listening on %s", ln.Addr())
+}
+
+func (p *Plugin) stopExporter() {
+	if p.exporter == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	err := p.exporter.Shutdown(ctx)
+	if err != nil {
+		p.Warningf("exporter shutdown failed: %s", err)
+	}
+
+	p.exporter = nil
+}
+
+// serveMetrics scrapes every exported session and writes the result in the OpenMetrics text format.
+func (p *Plugin) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	mw := newMetricWriter()
+	opts := p.config()
+	ctx := handlers.WithConfig(r.Context(), opts.handlersConfig())
+
+	for _, name := range opts.exporterSessions() {
+		p.scrapeSession(ctx, mw, opts, name)
+	}
+
+	w.Header().Set("Content-Type", openMetricsContentType)
+
+	err := mw.writeTo(w)
+	if err != nil {
+		p.Debugf("cannot write exporter response: %s", err)
+	}
+}
+
+func (p *Plugin) scrapeSession(ctx context.Context, mw *metricWriter, opts *PluginOptions, name string) {
+	label := name
+	if label == "" {
+		label = defaultSessionLabel
+	}
+
+	conn, err := p.sessionConn(name, opts)
+	if err != nil {
+		p.Debugf("exporter cannot connect to session %s: %s", label, err)
+		mw.add("mongodb_up", typeGauge, "Whether the server of the session is reachable.", 0, "session", label)
+
+		return
+	}
+
+	defer p.connMgr.Release(conn)
+
+	mw.add("mongodb_up", typeGauge, "Whether the server of the session is reachable.", 1, "session", label)
+
+	ctx, cancel := context.WithTimeout(ctx, conn.getTimeout())
+	defer cancel()
+
+	collectSession(ctx, mw, label, conn, opts.Exporter.CollectionStats)
+}
+
+func (p *Plugin) sessionConn(name string, opts *PluginOptions) (*MongoConn, error) {
+	connURI, params, err := sessionParams(name, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.connMgr.GetConnection(*connURI, params)
+}
+
+// collectSession runs the handlers of the exported keys on the connection and adds their results.
+// A failed handler only skips its own metrics, which is reported by mongodb_collector_success.
+func collectSession(ctx context.Context, mw *metricWriter, session string, conn handlers.Session, collStats bool) {
+	success := func(collector string, err error) bool {
+		value := 1.0
+		if err != nil {
+			value = 0
+		}
+
+		mw.add("mongodb_collector_success", typeGauge, "Whether the last collection of the data succeeded.",
+			value, "session", session, "collector", collector)
+
+		return err == nil
+	}
+
+	doc, err := runHandler(ctx, keyServerStatus, conn, nil)
+	if success("server_status", err) {
+		addPathMetrics(mw, doc, serverStatusMetrics, "session", session)
+	}
+
+	doc, err = runHandler(ctx, keyConnPoolStats, conn, nil)
+	if success("connpool", err) {
+		addPathMetrics(mw, doc, connPoolMetrics, "session", session)
+	}
+
+	doc, err = runHandler(ctx, keyReplSetStatus, conn, nil)
+	if success("replset", err) {
+		members, _ := doc["members"].([]any)
+		for _, m := range members {
+			member, _ := m.(map[string]any)
+			name, _ := member["name"].(string)
+			addPathMetrics(mw, member, memberMetrics, "session", session, "member", name)
+		}
+
+		// The oplog exists on replica set members only.
+		if len(members) > 0 {
+			doc, err = runHandler(ctx, keyOplogStats, conn, nil)
+			if success("oplog", err) {
+				addPathMetrics(mw, doc, []pathMetric{{
+					"timediff", "mongodb_oplog_window_seconds", typeGauge,
+					"Time between the first and the last oplog entries.", nil,
+				}}, "session", session)
+			}
+		}
+	}
+
+	dbs, err := conn.DatabaseNames(ctx)
+	if !success("databases", err) {
+		return
+	}
+
+	sort.Strings(dbs)
+
+	// Every database gets an equal share of the time left, so that a slow or large database cannot use up
+	// the time of the ones after it.
+	for i, db := range dbs {
+		dbCtx, cancel := handlers.ShareContext(ctx, len(dbs)-i)
+		collectDatabase(dbCtx, mw, session, conn, db, collStats)
+		cancel()
+	}
+}
+
+func collectDatabase(
+	ctx context.Context, mw *metricWriter, session string, conn handlers.Session, db string, collStats bool,
+) {
+	doc, err := runHandler(ctx, keyDatabaseStats, conn, map[string]string{"Database": db})
+	if err == nil {
+		addPathMetrics(mw, doc, dbStatsMetrics, "session", session, "db", db)
+	}
+
+	if !collStats {
+		return
+	}
+
+	cols, err := conn.DB(db).CollectionNames(ctx)
+	if err != nil {
+		return
+	}
+
+	sort.Strings(cols)
+
+	for i, col := range cols {
+		colCtx, cancel := handlers.ShareContext(ctx, len(cols)-i)
+		doc, err := runHandler(colCtx, keyCollectionStats, conn, map[string]string{"Database": db, "Collection": col})
+
+		cancel()
+
+		if err == nil {
+			addPathMetrics(mw, doc, collStatsMetrics, "session", session, "db", db, "collection", col)
+		}
+	}
+}
+
+// runHandler executes the handler of the metric key and decodes its JSON result.
+func runHandler(
+	ctx context.Context, key string, conn handlers.Session, params map[string]string,
+) (map[string]any, error) {
+	res, err := metricHandlers[key](ctx, conn, params)
+	if err != nil {
+		return nil, err
+	}
+
+	s, _ := res.(string)
+
+	var doc map[string]any
+
+	err = json.Unmarshal([]byte(s), &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// addPathMetrics adds a sample for every metric whose dotted path holds a number in the document.
+func addPathMetrics(mw *metricWriter, doc map[string]any, metrics []pathMetric, labels ...string) {
+	for _, m := range metrics {
+		v, ok := lookupNumber(doc, m.path)
+		if !ok {
+			continue
+		}
+
+		mw.add(m.name, m.typ, m.help, v, append(append([]string{}, labels...), m.labels...)...)
+	}
+}
+
+func lookupNumber(doc map[string]any, path string) (float64, bool) {
+	var cur any = doc
+
+	for _, key := range strings.Split(path, ".") {
+		m, ok := cur.(map[string]any)
+		if !ok {
+			return 0, false
+		}
+
+		cur = m[key]
+	}
+
+	switch v := cur.(type) {
+	case float64:
+		return v, true
+	case map[string]any:
+		return extJSONNumber(v)
+	case bool:
+		if v {
+			return 1, true
+		}
+
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// extJSONNumber returns the value of a number rendered in the canonical Extended JSON mode.
+func extJSONNumber(v map[string]any) (float64, bool) {
+	if len(v) != 1 {
+		return 0, false
+	}
+
+	for _, key := range []string{"$numberInt", "$numberLong", "$numberDouble", "$numberDecimal"} {
+		if s, ok := v[key].(string); ok {
+			f, err := strconv.ParseFloat(s, 64)
+
+			return f, err == nil
+		}
+	}
+
+	return 0, false
+}
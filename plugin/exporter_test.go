@@ -0,0 +1,319 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.zabbix.com/plugin/mongodb/plugin/handlers"
+	"golang.zabbix.com/sdk/log"
+)
+
+func TestMetricWriter(t *testing.T) {
+	t.Parallel()
+
+	w := newMetricWriter()
+	w.add("mongodb_up", typeGauge, "Up.", 1, "session", `a"b\c`)
+	w.add("mongodb_asserts", typeCounter, "Asserts.", 2, "session", "s", "type", "user")
+	w.add("mongodb_up", typeGauge, "Up.", 0.5, "session", "x")
+
+	var b strings.Builder
+
+	err := w.writeTo(&b)
+	if err != nil {
+		t.Fatalf("writeTo() error = %v", err)
+	}
+
+	want := `# TYPE mongodb_up gauge
+# HELP mongodb_up Up.
+mongodb_up{session="a\"b\\c"} 1
+mongodb_up{session="x"} 0.5
+# TYPE mongodb_asserts counter
+# HELP mongodb_asserts Asserts.
+mongodb_asserts_total{session="s",type="user"} 2
+# EOF
+`
+
+	if diff := cmp.Diff(want, b.String()); diff != "" {
+		t.Fatalf("writeTo() = %s", diff)
+	}
+}
+
+func TestCollectSession(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		collStats bool
+		fail      string
+	}
+
+	tests := []struct {
+		name    string
+		args    args
+		want    []string
+		notWant []string
+	}{
+		{
+			"+standalone",
+			args{false, ""},
+			[]string{
+				`mongodb_collector_success{session="s",collector="server_status"} 1`,
+				`mongodb_uptime_seconds{session="s"} 100`,
+				`mongodb_op_counters_total{session="s",type="insert"} 7`,
+				`mongodb_connections{session="s",state="current"} 3`,
+				`mongodb_connpool_connections{session="s",state="in_use"} 2`,
+				`mongodb_connpool_connections_created_total{session="s"} 9`,
+				`mongodb_collector_success{session="s",collector="replset"} 1`,
+				`mongodb_db_objects{session="s",db="app"} 42`,
+				`mongodb_db_data_size_bytes{session="s",db="app"} 1024`,
+			},
+			[]string{"mongodb_collection_", "mongodb_oplog_window_seconds", `collector="oplog"`},
+		},
+		{
+			"+collection stats",
+			args{true, ""},
+			[]string{
+				`mongodb_collection_documents{session="s",db="app",collection="users"} 5`,
+				`mongodb_collection_size_bytes{session="s",db="app",collection="users"} 50`,
+			},
+			nil,
+		},
+		{
+			"-server status failure",
+			args{false, "serverStatus"},
+			[]string{
+				`mongodb_collector_success{session="s",collector="server_status"} 0`,
+				`mongodb_connpool_connections{session="s",state="in_use"} 2`,
+			},
+			[]string{"mongodb_uptime_seconds"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn := newExporterMockConn(tt.args.fail)
+
+			w := newMetricWriter()
+			collectSession(context.Background(), w, "s", conn, tt.args.collStats)
+
+			var b strings.Builder
+
+			err := w.writeTo(&b)
+			if err != nil {
+				t.Fatalf("writeTo() error = %v", err)
+			}
+
+			got := b.String()
+
+			for _, line := range tt.want {
+				if !strings.Contains(got, line+"\n") {
+					t.Errorf("collectSession() output misses %q:\n%s", line, got)
+				}
+			}
+
+			for _, s := range tt.notWant {
+				if strings.Contains(got, s) {
+					t.Errorf("collectSession() output contains %q:\n%s", s, got)
+				}
+			}
+		})
+	}
+}
+
+func TestCollectSession_slowDatabase(t *testing.T) {
+	t.Parallel()
+
+	conn := newExporterMockConn("")
+
+	// A database which never answers must not use up the time of the ones after it.
+	conn.DB("archive").(*handlers.MockMongoDatabase).BlockRun = true
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	w := newMetricWriter()
+	collectSession(ctx, w, "s", conn, true)
+
+	var b strings.Builder
+
+	err := w.writeTo(&b)
+	if err != nil {
+		t.Fatalf("writeTo() error = %v", err)
+	}
+
+	got := b.String()
+
+	for _, line := range []string{
+		`mongodb_db_objects{session="s",db="test"} 42`,
+		`mongodb_collection_documents{session="s",db="app",collection="users"} 5`,
+	} {
+		if !strings.Contains(got, line+"\n") {
+			t.Errorf("collectSession() output misses %q:\n%s", line, got)
+		}
+	}
+
+	if strings.Contains(got, `db="archive"`) {
+		t.Errorf("collectSession() output contains metrics of the slow database:\n%s", got)
+	}
+
+	if ctx.Err() != nil {
+		t.Fatalf("collectSession() used up the time of the scrape")
+	}
+}
+
+func TestPlugin_serveMetrics(t *testing.T) {
+	t.Parallel()
+
+	p := &Plugin{}
+	p.options.Store(&PluginOptions{
+		Timeout: 1,
+		Sessions: map[string]Session{
+			"down": {URI: "tcp://127.0.0.1:1"},
+		},
+	})
+	p.Logger = log.New("test")
+	p.connMgr = NewConnManager(time.Minute, time.Second, hkInterval*time.Second, p.Logger)
+
+	defer p.connMgr.Destroy()
+
+	rec := httptest.NewRecorder()
+	p.serveMetrics(rec, httptest.NewRequest(http.MethodGet, exporterPath, nil))
+
+	if ct := rec.Header().Get("Content-Type"); ct != openMetricsContentType {
+		t.Fatalf("serveMetrics() content type = %q", ct)
+	}
+
+	body := rec.Body.String()
+	if !strings.Contains(body, `mongodb_up{session="down"} 0`) || !strings.HasSuffix(body, "# EOF\n") {
+		t.Fatalf("serveMetrics() = %s", body)
+	}
+}
+
+func TestPluginOptions_exporterSessions(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		opts PluginOptions
+		want []string
+	}{
+		{"+default", PluginOptions{}, []string{""}},
+		{
+			"+all named",
+			PluginOptions{Sessions: map[string]Session{"b": {}, "a": {}}},
+			[]string{"a", "b"},
+		},
+		{
+			"+listed",
+			PluginOptions{
+				Sessions: map[string]Session{"b": {}, "a": {}},
+				Exporter: ExporterOptions{Sessions: " b, ,"},
+			},
+			[]string{"b"},
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, tt.opts.exporterSessions()); diff != "" {
+				t.Fatalf("exporterSessions() = %s", diff)
+			}
+		})
+	}
+}
+
+func TestLookupNumber(t *testing.T) {
+	t.Parallel()
+
+	doc := map[string]any{
+		"a": map[string]any{"b": 2.5, "ok": true, "s": "x"},
+		"l": map[string]any{"$numberLong": "9007199254740993"},
+		"d": map[string]any{"$numberDouble": "Infinity"},
+		"x": map[string]any{"$numberLong": "1", "y": "2"},
+	}
+
+	tests := []struct {
+		path   string
+		want   float64
+		wantOk bool
+	}{
+		{"a.b", 2.5, true},
+		{"a.ok", 1, true},
+		{"a.s", 0, false},
+		{"a.b.c", 0, false},
+		{"l", 9007199254740993, true},
+		{"x", 0, false},
+		{"missing", 0, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.path, func(t *testing.T) {
+			t.Parallel()
+
+			got, ok := lookupNumber(doc, tt.path)
+			if got != tt.want || ok != tt.wantOk {
+				t.Fatalf("lookupNumber() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOk)
+			}
+		})
+	}
+}
+
+func newExporterMockConn(fail string) *handlers.MockConn {
+	docs := map[string]bson.M{
+		"serverStatus": {
+			"uptime":      100,
+			"connections": bson.M{"current": 3, "available": 10},
+			"opcounters":  bson.M{"insert": 7},
+		},
+		"connPoolStats": {"totalInUse": 2, "totalCreated": 9},
+		"dbStats":       {"objects": 42, "dataSize": 1024},
+		"collStats":     {"count": 5, "size": 50},
+	}
+
+	run := func(_, cmd string) ([]byte, error) {
+		if cmd == fail {
+			return nil, errors.New("fail")
+		}
+
+		if cmd == "replSetGetStatus" {
+			return nil, errors.New("not running with --replSet")
+		}
+
+		return bson.Marshal(docs[cmd])
+	}
+
+	conn := handlers.NewMockConn()
+
+	for _, name := range []string{"admin", "test", "app"} {
+		db := conn.DB(name).(*handlers.MockMongoDatabase)
+		db.RunFunc = run
+	}
+
+	conn.DB("app").C("users")
+
+	return conn
+}
@@ -0,0 +1,168 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"golang.zabbix.com/plugin/mongodb/plugin/handlers"
+	"golang.zabbix.com/sdk/errs"
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+const (
+	formatParam = "Format"
+	fieldsParam = "Fields"
+
+	formatJSON = "json"
+	formatFlat = "flat"
+	formatLLD  = "lld"
+)
+
+type lldPair struct {
+	Key   string `json:"{#KEY}"`
+	Value string `json:"{#VALUE}"`
+}
+
+// formatOutput is the output stage shared by all keys. It projects the JSON result of a handler to the
+// selected fields, unless the handler already did, and converts it to the requested output format. Results of
+// keys without these parameters, or with their default values, are returned unchanged.
+func formatOutput(result any, proj *handlers.Projection, format string) (any, error) {
+	project := len(proj.Paths) > 0 && !proj.Applied
+
+	if !project && (format == "" || format == formatJSON) {
+		return result, nil
+	}
+
+	s, ok := result.(string)
+	if !ok {
+		return nil, errs.Wrap(zbxerr.ErrorCannotParseResult, "fields and format parameters require a JSON result")
+	}
+
+	dec := json.NewDecoder(bytes.NewReader([]byte(s)))
+	dec.UseNumber()
+
+	var out any
+
+	err := dec.Decode(&out)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotUnmarshalJSON.Wrap(err)
+	}
+
+	if project {
+		out = handlers.ProjectFields(out, proj.Paths)
+	}
+
+	switch format {
+	case formatFlat, formatLLD:
+		flat := make(map[string]any)
+		flatten(flat, "", out)
+
+		out = flat
+		if format == formatLLD {
+			out = lldPairs(flat)
+		}
+	}
+
+	jsonRes, err := json.Marshal(out)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+// fieldsValidator rejects invalid Fields parameters before the request is run.
+type fieldsValidator struct{}
+
+func (fieldsValidator) Validate(value *string) error {
+	if value == nil {
+		return nil
+	}
+
+	_, err := handlers.ParseFields(*value)
+
+	return err
+}
+
+// flatten adds the scalar values of the document to out, keyed by their dot separated path.
+// Array elements are keyed by their index. Empty objects and arrays are kept as they are, so that their paths
+// do not disappear from the output.
+func flatten(out map[string]any, prefix string, v any) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+
+		return prefix + "." + key
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		if len(val) == 0 && prefix != "" {
+			out[prefix] = val
+		}
+
+		for k, item := range val {
+			flatten(out, join(k), item)
+		}
+	case []any:
+		if len(val) == 0 && prefix != "" {
+			out[prefix] = val
+		}
+
+		for i, item := range val {
+			flatten(out, join(strconv.Itoa(i)), item)
+		}
+	default:
+		out[prefix] = val
+	}
+}
+
+func lldPairs(flat map[string]any) []lldPair {
+	keys := make([]string, 0, len(flat))
+	for k := range flat {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	pairs := make([]lldPair, 0, len(keys))
+
+	for _, k := range keys {
+		var value string
+
+		switch v := flat[k].(type) {
+		case nil:
+		case string:
+			value = v
+		case json.Number:
+			value = v.String()
+		case bool:
+			value = strconv.FormatBool(v)
+		case map[string]any:
+			value = "{}"
+		case []any:
+			value = "[]"
+		}
+
+		pairs = append(pairs, lldPair{Key: k, Value: value})
+	}
+
+	return pairs
+}
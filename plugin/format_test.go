@@ -0,0 +1,158 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.zabbix.com/plugin/mongodb/plugin/handlers"
+)
+
+func TestFormatOutput(t *testing.T) {
+	t.Parallel()
+
+	doc := `{"host":"db1","ok":1,"mem":{"resident":85,"supported":true},"members":[{"name":"a"},{"name":"b"}],` +
+		`"x":null,"big":9007199254740993}`
+
+	tests := []struct {
+		name    string
+		result  any
+		fields  string
+		format  string
+		want    any
+		wantErr bool
+	}{
+		{"+emptyFormat", doc, "", "", doc, false},
+		{"+json", doc, "", formatJSON, doc, false},
+		{"+jsonNotString", 1, "", formatJSON, 1, false},
+		{
+			"+flat",
+			doc,
+			"",
+			formatFlat,
+			`{"big":9007199254740993,"host":"db1","mem.resident":85,"mem.supported":true,` +
+				`"members.0.name":"a","members.1.name":"b","ok":1,"x":null}`,
+			false,
+		},
+		{
+			"+lld",
+			`{"b":{"c":2},"a":"x","d":false}`,
+			"",
+			formatLLD,
+			`[{"{#KEY}":"a","{#VALUE}":"x"},{"{#KEY}":"b.c","{#VALUE}":"2"},{"{#KEY}":"d","{#VALUE}":"false"}]`,
+			false,
+		},
+		{"+flatArray", `[1,[2]]`, "", formatFlat, `{"0":1,"1.0":2}`, false},
+		{"+flatEmpty", `{}`, "", formatFlat, `{}`, false},
+		{
+			"+flatEmptyValues",
+			`{"members":[],"tags":{},"set":{"votes":[],"name":"rs0"}}`,
+			"",
+			formatFlat,
+			`{"members":[],"set.name":"rs0","set.votes":[],"tags":{}}`,
+			false,
+		},
+		{
+			"+lldEmptyValues",
+			`{"members":[],"tags":{}}`,
+			"",
+			formatLLD,
+			`[{"{#KEY}":"members","{#VALUE}":"[]"},{"{#KEY}":"tags","{#VALUE}":"{}"}]`,
+			false,
+		},
+		{"-notString", 1, "", formatFlat, nil, true},
+		{"-invalidJSON", "Connection failed.", "", formatLLD, nil, true},
+		{"+fields", doc, "host, mem.resident,$.members[*].name", "", `{"host":"db1","mem":{"resident":85},` +
+			`"members":[{"name":"a"},{"name":"b"}]}`, false},
+		{"+fieldsIndex", doc, "$['members'][1].name", "", `{"members":[{"name":"b"}]}`, false},
+		{"+fieldsMissing", doc, "missing.path", "", `{}`, false},
+		{"+fieldsFlat", doc, "mem", formatFlat, `{"mem.resident":85,"mem.supported":true}`, false},
+		{"-fieldsNotString", 1, "host", "", nil, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			paths, err := handlers.ParseFields(tt.fields)
+			if err != nil {
+				t.Fatalf("ParseFields() error = %v", err)
+			}
+
+			got, err := formatOutput(tt.result, &handlers.Projection{Paths: paths}, tt.format)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("formatOutput() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("formatOutput() = %s", diff)
+			}
+		})
+	}
+}
+
+func TestFormatOutput_applied(t *testing.T) {
+	t.Parallel()
+
+	// A handler already projected the document, so the result must not be projected again.
+	proj := &handlers.Projection{Paths: []handlers.FieldPath{{"missing"}}, Applied: true}
+	doc := `{"mem":{"resident":85}}`
+
+	got, err := formatOutput(doc, proj, formatJSON)
+	if err != nil || got != doc {
+		t.Fatalf("formatOutput() = %v, %v, want %s", got, err, doc)
+	}
+
+	got, err = formatOutput(doc, proj, formatFlat)
+	if err != nil || got != `{"mem.resident":85}` {
+		t.Fatalf("formatOutput() = %v, %v, want flat document", got, err)
+	}
+}
+
+func TestMetrics_formatParam(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		key     string
+		params  []string
+		want    string
+		wantErr bool
+	}{
+		{"+default", keyServerStatus, []string{"tcp://localhost"}, formatJSON, false},
+		{"+flat", keyServerStatus, []string{"tcp://localhost", "", "", formatFlat}, formatFlat, false},
+		{"+afterKeyParams", keyDatabaseStats, []string{"tcp://localhost", "", "", "app", formatLLD}, formatLLD, false},
+		{"+pluginStats", keyPluginStats, []string{formatFlat}, formatFlat, false},
+		{"+notSupported", keyPing, []string{"tcp://localhost"}, "", false},
+		{"-invalid", keyServerStatus, []string{"tcp://localhost", "", "", "xml"}, "", true},
+		{"-invalidFields", keyServerStatus, []string{"tcp://localhost", "", "", "", "mem..x"}, "", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			params, _, _, err := metrics[tt.key].EvalParams(tt.params, map[string]Session{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EvalParams() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if params[formatParam] != tt.want {
+				t.Fatalf("EvalParams() format = %q, want %q", params[formatParam], tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import "context"
+
+// Config holds the plugin settings handlers depend on. The plugin passes a snapshot of them with the context
+// of every request, so a configuration reload never changes the settings of a request in progress.
+type Config struct {
+	// CustomQueriesPath is a directory containing named aggregation pipelines and commands, one per file.
+	CustomQueriesPath string
+
+	// AllowedCommands lists commands CommandHandler and custom queries are allowed to run.
+	AllowedCommands []string
+
+	// BaselinesPath is a directory containing the baseline and expected values files of drift checks.
+	BaselinesPath string
+
+	// JSONMode selects how documents returned by the server are rendered as JSON.
+	JSONMode string
+}
+
+type configKey struct{}
+
+// defaultConfig is used by requests without a Config, like the ones of tests.
+var defaultConfig = &Config{AllowedCommands: DefaultAllowedCommands, JSONMode: JSONModeLegacy}
+
+// WithConfig returns a copy of the context carrying the settings.
+func WithConfig(ctx context.Context, cfg *Config) context.Context {
+	return context.WithValue(ctx, configKey{}, cfg)
+}
+
+// configFrom returns the settings carried by the context, or the default ones.
+func configFrom(ctx context.Context) *Config {
+	if cfg, ok := ctx.Value(configKey{}).(*Config); ok && cfg != nil {
+		return cfg
+	}
+
+	return defaultConfig
+}
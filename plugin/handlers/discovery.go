@@ -0,0 +1,73 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// systemDatabases are the databases skipped by discovery handlers when system entities are skipped.
+var systemDatabases = map[string]bool{"admin": true, "local": true, "config": true}
+
+// discoveryOptions are the filters shared by the discovery handlers, read from the Include, Exclude,
+// SkipSystem and MaxResults parameters.
+type discoveryOptions struct {
+	filter     *nameFilter
+	skipSystem bool
+	maxResults int
+}
+
+func newDiscoveryOptions(params map[string]string) (*discoveryOptions, error) {
+	filter, err := newNameFilter(params["Include"], params["Exclude"])
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &discoveryOptions{filter: filter, skipSystem: params["SkipSystem"] == "true"}
+
+	if params["MaxResults"] != "" {
+		opts.maxResults, err = strconv.Atoi(params["MaxResults"])
+		if err != nil || opts.maxResults < 0 {
+			return nil, fmt.Errorf("invalid maximum number of results %q", params["MaxResults"])
+		}
+	}
+
+	return opts, nil
+}
+
+// full reports if the number of results reached the maximum.
+func (o *discoveryOptions) full(n int) bool {
+	return o.maxResults > 0 && n >= o.maxResults
+}
+
+func (o *discoveryOptions) matchDatabase(db string) bool {
+	return !(o.skipSystem && systemDatabases[db])
+}
+
+// mayMatchCollections reports whether any collection of the database can be selected, judged from the
+// database name alone, so that databases which cannot match are not listed at all.
+func (o *discoveryOptions) mayMatchCollections(db string) bool {
+	return o.matchDatabase(db) && !o.filter.rejectsPrefix(db+".")
+}
+
+func (o *discoveryOptions) matchCollection(db, col string) bool {
+	if o.skipSystem && (systemDatabases[db] || strings.HasPrefix(col, "system.")) {
+		return false
+	}
+
+	return o.filter.match(db + "." + col)
+}
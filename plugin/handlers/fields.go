@@ -0,0 +1,353 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FieldWildcard matches every member of an object or element of an array in a FieldPath.
+const FieldWildcard = "*"
+
+var errInvalidFieldPath = errors.New("invalid field path")
+
+// FieldPath is a parsed projection path, one item per object member name or array index.
+type FieldPath []string
+
+// Projection carries the paths of the Fields parameter of a request. Documents returned by the server are
+// projected before they are rendered, and Applied tells the output stage that it does not need to do it again.
+type Projection struct {
+	Paths   []FieldPath
+	Applied bool
+}
+
+type projectionKey struct{}
+
+// WithProjection returns a copy of the context carrying the projection of the request.
+func WithProjection(ctx context.Context, p *Projection) context.Context {
+	return context.WithValue(ctx, projectionKey{}, p)
+}
+
+func projectionFrom(ctx context.Context) *Projection {
+	p, _ := ctx.Value(projectionKey{}).(*Projection)
+
+	return p
+}
+
+// unselected marks array elements not matched by any path until the projection is compacted.
+type unselected struct{}
+
+// ParseFields parses a comma separated list of dotted paths, like "mem.resident" or "members[*].name",
+// or of JSONPath expressions limited to member names, indexes and wildcards, like "$['mem'].resident".
+func ParseFields(s string) ([]FieldPath, error) {
+	var paths []FieldPath
+
+	for _, expr := range splitFields(s) {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+
+		path, err := parseFieldPath(expr)
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
+
+// splitFields splits the list at commas which are not quoted inside brackets.
+func splitFields(s string) []string {
+	var (
+		list  []string
+		quote rune
+		start int
+	)
+
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ',':
+			list = append(list, s[start:i])
+			start = i + 1
+		}
+	}
+
+	return append(list, s[start:])
+}
+
+func parseFieldPath(expr string) (FieldPath, error) {
+	rest := expr
+	if strings.HasPrefix(rest, "$") {
+		rest = rest[1:]
+	} else {
+		rest = "." + rest
+	}
+
+	var path FieldPath
+
+	for rest != "" {
+		var (
+			segment string
+			err     error
+		)
+
+		switch rest[0] {
+		case '.':
+			segment, rest = readMemberName(rest[1:])
+		case '[':
+			segment, rest, err = readBracket(rest[1:])
+		default:
+			err = errInvalidFieldPath
+		}
+
+		if err != nil || segment == "" {
+			return nil, fmt.Errorf("%w: %q", errInvalidFieldPath, expr)
+		}
+
+		path = append(path, segment)
+	}
+
+	return path, nil
+}
+
+func readMemberName(s string) (string, string) {
+	end := strings.IndexAny(s, ".[")
+	if end == -1 {
+		return s, ""
+	}
+
+	return s[:end], s[end:]
+}
+
+func readBracket(s string) (string, string, error) {
+	if s != "" && (s[0] == '\'' || s[0] == '"') {
+		end := strings.IndexByte(s[1:], s[0])
+		if end == -1 || !strings.HasPrefix(s[end+2:], "]") {
+			return "", "", errInvalidFieldPath
+		}
+
+		return s[1 : end+1], s[end+3:], nil
+	}
+
+	end := strings.IndexByte(s, ']')
+	if end == -1 {
+		return "", "", errInvalidFieldPath
+	}
+
+	segment := s[:end]
+	if segment != FieldWildcard {
+		if _, err := strconv.Atoi(segment); err != nil {
+			return "", "", errInvalidFieldPath
+		}
+	}
+
+	return segment, s[end+1:], nil
+}
+
+// ProjectFields returns a copy of the decoded JSON or BSON document with only the values selected by the paths.
+// Objects and arrays keep their structure, unmatched paths are ignored. Selected BSON values keep their types,
+// while BSON documents and arrays on the way to them become maps and slices.
+func ProjectFields(doc any, paths []FieldPath) any {
+	if len(paths) == 0 {
+		return doc
+	}
+
+	var out any
+
+	for _, path := range paths {
+		if res, ok := project(out, doc, path); ok {
+			out = res
+		}
+	}
+
+	if out == nil {
+		return map[string]any{}
+	}
+
+	return compact(out)
+}
+
+func project(out, v any, path FieldPath) (any, bool) {
+	if len(path) == 0 {
+		return v, true
+	}
+
+	if plain, ok := plainValue(v); ok {
+		v = plain
+	}
+
+	switch val := v.(type) {
+	case map[string]any:
+		o, _ := out.(map[string]any)
+		matched := o != nil
+
+		if o == nil {
+			o = make(map[string]any)
+		}
+
+		for k, item := range val {
+			if path[0] != FieldWildcard && path[0] != k {
+				continue
+			}
+
+			if res, ok := project(o[k], item, path[1:]); ok {
+				o[k] = res
+				matched = true
+			}
+		}
+
+		return o, matched
+	case []any:
+		o, _ := out.([]any)
+		matched := o != nil
+
+		if len(o) != len(val) {
+			o = make([]any, len(val))
+			for i := range o {
+				o[i] = unselected{}
+			}
+		}
+
+		for i, item := range val {
+			if path[0] != FieldWildcard && path[0] != strconv.Itoa(i) {
+				continue
+			}
+
+			prev := o[i]
+			if _, ok := prev.(unselected); ok {
+				prev = nil
+			}
+
+			if res, ok := project(prev, item, path[1:]); ok {
+				o[i] = res
+				matched = true
+			}
+		}
+
+		return o, matched
+	default:
+		return out, out != nil
+	}
+}
+
+// plainValue converts a BSON document or array to the map or slice of its values, so that it can be projected.
+func plainValue(v any) (any, bool) {
+	switch val := v.(type) {
+	case map[string]any, []any:
+		return val, true
+	case *bson.M:
+		return map[string]any(*val), true
+	case bson.M:
+		return map[string]any(val), true
+	case primitive.D:
+		doc := make(map[string]any, len(val))
+		for _, e := range val {
+			doc[e.Key] = e.Value
+		}
+
+		return doc, true
+	case primitive.A:
+		return []any(val), true
+	case []bson.M:
+		arr := make([]any, 0, len(val))
+		for _, doc := range val {
+			arr = append(arr, map[string]any(doc))
+		}
+
+		return arr, true
+	default:
+		return nil, false
+	}
+}
+
+// compact removes array elements which were not selected by any path.
+func compact(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, item := range val {
+			val[k] = compact(item)
+		}
+
+		return val
+	case []any:
+		res := make([]any, 0, len(val))
+
+		for _, item := range val {
+			if _, ok := item.(unselected); ok {
+				continue
+			}
+
+			res = append(res, compact(item))
+		}
+
+		return res
+	default:
+		return v
+	}
+}
+
+// serverStatusSections are the serverStatus sections which can be excluded from the reply.
+var serverStatusSections = []string{
+	"asserts", "connections", "electionMetrics", "extra_info", "flowControl", "globalLock", "locks",
+	"logicalSessionRecordCache", "mem", "metrics", "network", "opLatencies", "opReadConcernCounters", "opcounters",
+	"opcountersRepl", "oplogTruncation", "repl", "security", "storageEngine", "tcmalloc", "transactions",
+	"transportSecurity", "twoPhaseCommitCoordinator", "wiredTiger",
+}
+
+// serverStatusCommand returns the serverStatus command excluding the known sections not selected by the paths.
+func serverStatusCommand(paths []FieldPath) bson.D {
+	cmd := bson.D{
+		{Key: "serverStatus", Value: 1},
+		{Key: "recordStats", Value: 0},
+	}
+
+	if len(paths) == 0 {
+		return cmd
+	}
+
+	selected := make(map[string]bool)
+
+	for _, path := range paths {
+		if len(path) == 0 || path[0] == FieldWildcard {
+			return cmd
+		}
+
+		selected[path[0]] = true
+	}
+
+	for _, section := range serverStatusSections {
+		if !selected[section] {
+			cmd = append(cmd, bson.E{Key: section, Value: 0})
+		}
+	}
+
+	return cmd
+}
@@ -0,0 +1,156 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestParseFields(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		s       string
+		want    []FieldPath
+		wantErr bool
+	}{
+		{"+empty", "", nil, false},
+		{"+dotted", "mem.resident, uptime", []FieldPath{{"mem", "resident"}, {"uptime"}}, false},
+		{"+brackets", "members[*].optime[0]", []FieldPath{{"members", "*", "optime", "0"}}, false},
+		{
+			"+jsonPath",
+			`$.wiredTiger['cache'].x,$["a,b"][2]`,
+			[]FieldPath{{"wiredTiger", "cache", "x"}, {"a,b", "2"}},
+			false,
+		},
+		{"+root", "$", []FieldPath{nil}, false},
+		{"-emptySegment", "mem..resident", nil, true},
+		{"-recursiveDescent", "$..name", nil, true},
+		{"-badIndex", "members[a]", nil, true},
+		{"-unclosedBracket", "members[0", nil, true},
+		{"-unclosedQuote", "$['mem]", nil, true},
+		{"-noSeparator", "$mem", nil, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseFields(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("ParseFields() = %s", diff)
+			}
+		})
+	}
+}
+
+func TestProjectFields(t *testing.T) {
+	t.Parallel()
+
+	doc := func() any {
+		return map[string]any{
+			"host": "db1",
+			"mem":  map[string]any{"resident": 85.0, "virtual": 1024.0},
+			"members": []any{
+				map[string]any{"name": "a", "state": 1.0},
+				map[string]any{"name": "b", "state": 2.0},
+			},
+		}
+	}
+
+	tests := []struct {
+		name  string
+		paths []FieldPath
+		want  any
+	}{
+		{"+none", nil, doc()},
+		{"+scalar", []FieldPath{{"host"}}, map[string]any{"host": "db1"}},
+		{
+			"+merged",
+			[]FieldPath{{"mem", "resident"}, {"members", "1", "state"}, {"members", "1", "name"}},
+			map[string]any{
+				"mem":     map[string]any{"resident": 85.0},
+				"members": []any{map[string]any{"name": "b", "state": 2.0}},
+			},
+		},
+		{
+			"+wildcard",
+			[]FieldPath{{"members", "*", "name"}},
+			map[string]any{"members": []any{map[string]any{"name": "a"}, map[string]any{"name": "b"}}},
+		},
+		{
+			"+wholeAndPart",
+			[]FieldPath{{"mem"}, {"mem", "resident"}},
+			map[string]any{"mem": map[string]any{"resident": 85.0, "virtual": 1024.0}},
+		},
+		{"+missing", []FieldPath{{"host", "x"}, {"nope"}}, map[string]any{}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, ProjectFields(doc(), tt.paths)); diff != "" {
+				t.Fatalf("ProjectFields() = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_serverStatusCommand(t *testing.T) {
+	t.Parallel()
+
+	base := bson.D{{Key: "serverStatus", Value: 1}, {Key: "recordStats", Value: 0}}
+
+	tests := []struct {
+		name  string
+		paths []FieldPath
+		want  int
+	}{
+		{"+all", nil, len(base)},
+		{"+wildcard", []FieldPath{{"*", "x"}}, len(base)},
+		{"+root", []FieldPath{nil}, len(base)},
+		{"+sections", []FieldPath{{"mem", "resident"}, {"uptime"}}, len(base) + len(serverStatusSections) - 1},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := serverStatusCommand(tt.paths)
+			if len(got) != tt.want {
+				t.Fatalf("serverStatusCommand() = %v, want %d elements", got, tt.want)
+			}
+
+			if diff := cmp.Diff(base, got[:len(base)]); diff != "" {
+				t.Fatalf("serverStatusCommand() = %s", diff)
+			}
+
+			for _, e := range got[len(base):] {
+				if e.Key == "mem" || e.Value != 0 {
+					t.Fatalf("serverStatusCommand() excludes %v", e)
+				}
+			}
+		})
+	}
+}
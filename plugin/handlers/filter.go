@@ -0,0 +1,166 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+// maxLiteralPrefixes limits the number of alternatives tracked by literalPrefixes.
+const maxLiteralPrefixes = 64
+
+// nameFilter selects names matching the include pattern and not matching the exclude pattern.
+// Empty patterns are not applied.
+type nameFilter struct {
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+
+	// includePrefixes lists the literal prefixes every included name starts with, nil if they are unknown.
+	includePrefixes []string
+	// excludePrefixes lists the literal prefixes of names which are always excluded.
+	excludePrefixes []string
+}
+
+func newNameFilter(include, exclude string) (*nameFilter, error) {
+	var (
+		f   nameFilter
+		err error
+	)
+
+	if include != "" {
+		f.include, err = regexp.Compile(include)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern: %w", err)
+		}
+
+		f.includePrefixes, _ = literalPrefixes(include)
+	}
+
+	if exclude != "" {
+		f.exclude, err = regexp.Compile(exclude)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+		}
+
+		if prefixes, whole := literalPrefixes(exclude); whole {
+			f.excludePrefixes = prefixes
+		}
+	}
+
+	return &f, nil
+}
+
+func (f *nameFilter) match(name string) bool {
+	if f.include != nil && !f.include.MatchString(name) {
+		return false
+	}
+
+	return f.exclude == nil || !f.exclude.MatchString(name)
+}
+
+// rejectsPrefix reports whether no name starting with the prefix can be selected, so that names which are
+// expensive to list, like the collections of a database, can be skipped without listing them.
+func (f *nameFilter) rejectsPrefix(prefix string) bool {
+	for _, p := range f.excludePrefixes {
+		if strings.HasPrefix(prefix, p) {
+			return true
+		}
+	}
+
+	if f.includePrefixes == nil {
+		return false
+	}
+
+	for _, p := range f.includePrefixes {
+		if strings.HasPrefix(p, prefix) || strings.HasPrefix(prefix, p) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// literalPrefixes returns the literal prefixes one of which every match of a pattern anchored at the start,
+// like ^(app|crm)\., begins with. whole is true if any string starting with one of the prefixes matches.
+// Nil is returned for patterns which are not anchored or too complex to tell.
+func literalPrefixes(pattern string) ([]string, bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+
+	re = re.Simplify()
+
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat {
+		subs = re.Sub
+	}
+
+	if len(subs) == 0 || subs[0].Op != syntax.OpBeginText {
+		return nil, false
+	}
+
+	prefixes := []string{""}
+
+	for _, sub := range subs[1:] {
+		alts := literalAlternatives(sub)
+		if alts == nil || len(prefixes)*len(alts) > maxLiteralPrefixes {
+			return prefixes, false
+		}
+
+		next := make([]string, 0, len(prefixes)*len(alts))
+		for _, p := range prefixes {
+			for _, a := range alts {
+				next = append(next, p+a)
+			}
+		}
+
+		prefixes = next
+	}
+
+	return prefixes, true
+}
+
+// literalAlternatives returns the strings a case sensitive literal, or an alternation or group of them, matches.
+func literalAlternatives(re *syntax.Regexp) []string {
+	switch re.Op {
+	case syntax.OpLiteral:
+		if re.Flags&syntax.FoldCase != 0 {
+			return nil
+		}
+
+		return []string{string(re.Rune)}
+	case syntax.OpCapture:
+		return literalAlternatives(re.Sub[0])
+	case syntax.OpAlternate:
+		var alts []string
+
+		for _, sub := range re.Sub {
+			a := literalAlternatives(sub)
+			if a == nil {
+				return nil
+			}
+
+			alts = append(alts, a...)
+		}
+
+		return alts
+	default:
+		return nil
+	}
+}
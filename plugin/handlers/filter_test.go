@@ -0,0 +1,98 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import "testing"
+
+func Test_nameFilter(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		include string
+		exclude string
+		value   string
+		want    bool
+		wantErr bool
+	}{
+		{"+noPatterns", "", "", "app.users", true, false},
+		{"+included", `^app\.`, "", "app.users", true, false},
+		{"+notIncluded", `^app\.`, "", "crm.users", false, false},
+		{"+excluded", "", `\.tmp_`, "app.tmp_1", false, false},
+		{"+excludeWins", "^app", "users$", "app.users", false, false},
+		{"-invalidInclude", "(", "", "", false, true},
+		{"-invalidExclude", "", "[", "", false, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := newNameFilter(tt.include, tt.exclude)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newNameFilter() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got := f.match(tt.value); got != tt.want {
+				t.Fatalf("match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_nameFilter_rejectsPrefix(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		include string
+		exclude string
+		prefix  string
+		want    bool
+	}{
+		{"+noPatterns", "", "", "app.", false},
+		{"+included", `^app\.`, "", "app.", false},
+		{"+notIncluded", `^app\.`, "", "crm.", true},
+		{"+includedAlternation", `^(app|crm)\.`, "", "crm.", false},
+		{"+notIncludedAlternation", `^(app|crm)\.`, "", "hr.", true},
+		{"+includeShorterPrefix", `^ap`, "", "app.", false},
+		{"+includeLongerPrefix", `^app\.users$`, "", "app.", false},
+		{"+includeNotAnchored", `app\.`, "", "crm.", false},
+		{"+includeCaseInsensitive", `(?i)^app\.`, "", "APP.", false},
+		{"+excluded", "", `^(admin|local)\.`, "local.", true},
+		{"+excludeMatchesSome", "", `^app\.tmp`, "app.", false},
+		{"+excludeNotAnchored", "", `local\.`, "local.", false},
+		{"+excludeAnchoredEnd", "", `^local\.$`, "local.", false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := newNameFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("newNameFilter() error = %v", err)
+			}
+
+			if got := f.rejectsPrefix(tt.prefix); got != tt.want {
+				t.Fatalf("rejectsPrefix(%q) = %v, want %v", tt.prefix, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+var errNoCollStats = errors.New("$collStats returned no documents")
+
+type latencyBucket struct {
+	Micros int64 `bson:"micros" json:"micros"`
+	Count  int64 `bson:"count"  json:"count"`
+}
+
+type latencyOps struct {
+	Ops       int64           `bson:"ops"       json:"ops"`
+	Latency   int64           `bson:"latency"   json:"latency"`
+	Histogram []latencyBucket `bson:"histogram" json:"histogram,omitempty"`
+}
+
+type collectionScans struct {
+	Total       int64 `bson:"total"       json:"total"`
+	NonTailable int64 `bson:"nonTailable" json:"nonTailable"`
+}
+
+type collStatsLatency struct {
+	LatencyStats struct {
+		Reads        latencyOps `bson:"reads"`
+		Writes       latencyOps `bson:"writes"`
+		Commands     latencyOps `bson:"commands"`
+		Transactions latencyOps `bson:"transactions"`
+	} `bson:"latencyStats"`
+	QueryExecStats struct {
+		CollectionScans collectionScans `bson:"collectionScans"`
+	} `bson:"queryExecStats"`
+}
+
+type collectionLatency struct {
+	Reads           latencyOps      `json:"reads"`
+	Writes          latencyOps      `json:"writes"`
+	Commands        latencyOps      `json:"commands"`
+	Transactions    latencyOps      `json:"transactions"`
+	CollectionScans collectionScans `json:"collectionScans"`
+}
+
+// CollectionLatencyHandler returns the operation counts and cumulative latencies in microseconds of a collection,
+// summed over all shards. Latency histograms are included if the Histograms parameter is "true".
+// https://www.mongodb.com/docs/manual/reference/operator/aggregation/collStats/
+func CollectionLatencyHandler(ctx context.Context, s Session, params map[string]string, _ ...string) (any, error) {
+	stage := bson.D{
+		{Key: "latencyStats", Value: bson.D{{Key: "histograms", Value: params["Histograms"] == "true"}}},
+		{Key: "queryExecStats", Value: bson.D{}},
+	}
+
+	var docs []collStatsLatency
+
+	err := runCollStats(ctx, s, params, stage, &docs)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	var res collectionLatency
+
+	for _, d := range docs {
+		res.Reads.add(d.LatencyStats.Reads)
+		res.Writes.add(d.LatencyStats.Writes)
+		res.Commands.add(d.LatencyStats.Commands)
+		res.Transactions.add(d.LatencyStats.Transactions)
+		res.CollectionScans.Total += d.QueryExecStats.CollectionScans.Total
+		res.CollectionScans.NonTailable += d.QueryExecStats.CollectionScans.NonTailable
+	}
+
+	jsonRes, err := json.Marshal(res)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+// add sums the statistics of another shard, merging the histogram buckets.
+func (o *latencyOps) add(other latencyOps) {
+	o.Ops += other.Ops
+	o.Latency += other.Latency
+
+	if len(other.Histogram) == 0 {
+		return
+	}
+
+	counts := make(map[int64]int64)
+	for _, b := range append(o.Histogram, other.Histogram...) {
+		counts[b.Micros] += b.Count
+	}
+
+	o.Histogram = make([]latencyBucket, 0, len(counts))
+	for micros, count := range counts {
+		o.Histogram = append(o.Histogram, latencyBucket{Micros: micros, Count: count})
+	}
+
+	sort.Slice(o.Histogram, func(i, j int) bool { return o.Histogram[i].Micros < o.Histogram[j].Micros })
+}
+
+// collStatsPipeline returns the aggregation pipeline running the $collStats stage.
+func collStatsPipeline(stage bson.D) bson.A {
+	return bson.A{bson.D{{Key: "$collStats", Value: stage}}}
+}
+
+// runCollStats runs the $collStats stage on the collection, decoding one document per shard into docs.
+func runCollStats(ctx context.Context, s Session, params map[string]string, stage bson.D, docs any) error {
+	q, err := s.DB(params["Database"]).C(params["Collection"]).Aggregate(ctx, collStatsPipeline(stage))
+	if err != nil {
+		return err
+	}
+
+	err = q.Get(ctx, docs)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,199 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCollectionLatencyHandler(t *testing.T) {
+	t.Parallel()
+
+	shard := func(reads, histogram bson.A) bson.M {
+		return bson.M{
+			"ns": "app.users",
+			"latencyStats": bson.M{
+				"reads":    bson.M{"latency": int64(300), "ops": int64(3), "histogram": histogram},
+				"writes":   bson.M{"latency": int64(50), "ops": int32(1)},
+				"commands": bson.M{"latency": int64(0), "ops": int64(0)},
+			},
+			"queryExecStats": bson.M{"collectionScans": bson.M{"total": int64(2), "nonTailable": int64(1)}},
+		}
+	}
+
+	tests := []struct {
+		name       string
+		histograms bool
+		docs       bson.D
+		dataErr    error
+		want       any
+		wantErr    bool
+	}{
+		{
+			"+single",
+			false,
+			bson.D{{Key: "0", Value: shard(nil, nil)}},
+			nil,
+			`{"reads":{"ops":3,"latency":300},"writes":{"ops":1,"latency":50},"commands":{"ops":0,"latency":0},` +
+				`"transactions":{"ops":0,"latency":0},"collectionScans":{"total":2,"nonTailable":1}}`,
+			false,
+		},
+		{
+			"+shardsWithHistograms",
+			true,
+			bson.D{
+				{Key: "0", Value: shard(nil, bson.A{bson.M{"micros": int64(128), "count": int64(2)}})},
+				{Key: "1", Value: shard(nil, bson.A{
+					bson.M{"micros": int64(256), "count": int64(1)},
+					bson.M{"micros": int64(128), "count": int64(1)},
+				})},
+			},
+			nil,
+			`{"reads":{"ops":6,"latency":600,"histogram":[{"micros":128,"count":3},{"micros":256,"count":1}]},` +
+				`"writes":{"ops":2,"latency":100},"commands":{"ops":0,"latency":0},` +
+				`"transactions":{"ops":0,"latency":0},"collectionScans":{"total":4,"nonTailable":2}}`,
+			false,
+		},
+		{"-aggregationError", false, nil, errors.New("fail"), nil, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn := NewMockConn()
+
+			stage := bson.D{
+				{Key: "latencyStats", Value: bson.D{{Key: "histograms", Value: tt.histograms}}},
+				{Key: "queryExecStats", Value: bson.D{}},
+			}
+
+			q, err := conn.DB("app").C("users").Aggregate(context.Background(), collStatsPipeline(stage))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			q.(*MockMongoQuery).DataFunc = func() ([]byte, error) {
+				if tt.dataErr != nil {
+					return nil, tt.dataErr
+				}
+
+				return bson.Marshal(tt.docs)
+			}
+
+			params := map[string]string{"Database": "app", "Collection": "users", "Histograms": "false"}
+			if tt.histograms {
+				params["Histograms"] = "true"
+			}
+
+			got, err := CollectionLatencyHandler(context.Background(), conn, params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CollectionLatencyHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("CollectionLatencyHandler() = %s", diff)
+			}
+		})
+	}
+}
+
+func TestCollectionStatsHandler_collStatsStage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		version string
+		docs    bson.D
+		want    any
+		wantErr bool
+	}{
+		{
+			"+single",
+			"7.0.2",
+			bson.D{{Key: "0", Value: bson.M{
+				"ns":           "app.users",
+				"storageStats": bson.M{"count": int32(5), "size": int32(500), "nindexes": int32(1)},
+			}}},
+			`{"count":5,"nindexes":1,"ns":"app.users","ok":1,"size":500}`,
+			false,
+		},
+		{
+			"+sharded",
+			"6.2.0",
+			bson.D{
+				{Key: "0", Value: bson.M{
+					"ns": "app.users", "shard": "s1",
+					"storageStats": bson.M{"count": int32(5), "size": int64(500), "nindexes": int32(2)},
+				}},
+				{Key: "1", Value: bson.M{
+					"ns": "app.users", "shard": "s2",
+					"storageStats": bson.M{"count": int32(1), "size": int64(100), "nindexes": int32(2)},
+				}},
+			},
+			`{"count":6,"nindexes":2,"ns":"app.users","ok":1,"sharded":true,"shards":{"s1":{"count":5,"nindexes":2,` +
+				`"size":500},"s2":{"count":1,"nindexes":2,"size":100}},"size":600,"storageSize":0,` +
+				`"totalIndexSize":0,"totalSize":0}`,
+			false,
+		},
+		{"-noDocuments", "8.0.0", bson.D{}, nil, true},
+		{"+legacyCommand", "6.0.14", nil, `{"count":9,"ok":1}`, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn := NewMockConn()
+			conn.DB("admin").(*MockMongoDatabase).RunFunc = func(_, cmd string) ([]byte, error) {
+				return bson.Marshal(bson.M{"version": tt.version})
+			}
+			conn.DB("app").(*MockMongoDatabase).RunFunc = func(_, cmd string) ([]byte, error) {
+				if cmd != "collStats" {
+					return nil, errors.New("unexpected command " + cmd)
+				}
+
+				return bson.Marshal(bson.M{"count": int32(9), "ok": 1})
+			}
+
+			stage := bson.D{{Key: "storageStats", Value: bson.D{}}}
+
+			q, err := conn.DB("app").C("users").Aggregate(context.Background(), collStatsPipeline(stage))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			q.(*MockMongoQuery).DataFunc = func() ([]byte, error) {
+				return bson.Marshal(tt.docs)
+			}
+
+			got, err := CollectionStatsHandler(
+				context.Background(), conn, map[string]string{"Database": "app", "Collection": "users"},
+			)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CollectionStatsHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("CollectionStatsHandler() = %s", diff)
+			}
+		})
+	}
+}
@@ -16,15 +16,23 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"golang.zabbix.com/sdk/zbxerr"
 )
 
+// collStatsStageVersion is the first version deprecating the collStats command in favour of $collStats.
+const collStatsStageVersion = 6002000
+
 // CollectionStatsHandler
 // https://docs.mongodb.com/manual/reference/command/collStats/index.html
-func CollectionStatsHandler(ctx context.Context, s Session, params map[string]string) (any, error) {
+// Servers deprecating the collStats command are queried with the storageStats of the $collStats stage,
+// which is converted to the collStats output.
+func CollectionStatsHandler(ctx context.Context, s Session, params map[string]string, _ ...string) (any, error) {
+	if useCollStatsStage(ctx, s) {
+		return collectionStorageStats(ctx, s, params)
+	}
+
 	colStats := &bson.M{}
 	err := s.DB(params["Database"]).Run(
 		ctx,
@@ -37,10 +45,88 @@ func CollectionStatsHandler(ctx context.Context, s Session, params map[string]st
 		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
 	}
 
-	jsonRes, err := json.Marshal(colStats)
+	jsonRes, err := marshalDocument(ctx, colStats)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+// useCollStatsStage reports if the server deprecates the collStats command. The legacy command is used if
+// the version cannot be determined.
+func useCollStatsStage(ctx context.Context, s Session) bool {
+	v, err := cachedServerVersion(ctx, s)
+	if err != nil {
+		return false
+	}
+
+	return v.number() >= collStatsStageVersion
+}
+
+// collectionStorageStats returns the storageStats of the $collStats stage in the collStats output format.
+// Statistics of sharded collections are summed and listed per shard like mongos does for collStats.
+func collectionStorageStats(ctx context.Context, s Session, params map[string]string) (any, error) {
+	var docs []bson.M
+
+	err := runCollStats(ctx, s, params, bson.D{{Key: "storageStats", Value: bson.D{}}}, &docs)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	if len(docs) == 0 {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(errNoCollStats)
+	}
+
+	res := bson.M{}
+
+	if len(docs) == 1 {
+		if stats, ok := docs[0]["storageStats"].(bson.M); ok {
+			res = stats
+		}
+	} else {
+		res = mergeShardStorageStats(docs)
+	}
+
+	res["ns"] = docs[0]["ns"]
+	res["ok"] = 1
+
+	jsonRes, err := marshalDocument(ctx, res)
 	if err != nil {
 		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
 	}
 
 	return string(jsonRes), nil
 }
+
+// shardSummedStats are the storage statistics mongos sums over the shards of a collection.
+var shardSummedStats = []string{"count", "size", "storageSize", "totalIndexSize", "totalSize"}
+
+func mergeShardStorageStats(docs []bson.M) bson.M {
+	shards := bson.M{}
+	sums := make(map[string]int64)
+
+	var nindexes any
+
+	for _, d := range docs {
+		stats, _ := d["storageStats"].(bson.M)
+
+		shard, _ := d["shard"].(string)
+		shards[shard] = stats
+
+		for _, key := range shardSummedStats {
+			sums[key] += asInt64(stats[key])
+		}
+
+		if nindexes == nil {
+			nindexes = stats["nindexes"]
+		}
+	}
+
+	res := bson.M{"sharded": true, "shards": shards, "nindexes": nindexes}
+	for key, n := range sums {
+		res[key] = n
+	}
+
+	return res
+}
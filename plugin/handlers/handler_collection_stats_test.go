@@ -98,3 +98,54 @@ func Test_collectionStatsHandler(t *testing.T) {
 		})
 	}
 }
+
+func Test_useCollStatsStage(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		version   string
+		want      bool
+		wantCalls int
+	}{
+		{"+stage", "7.0.2", true, 1},
+		{"+command", "6.0.14", false, 1},
+		{"-invalidVersion", "x.y", false, 2},
+		{"-buildInfoFails", "", false, 2},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			calls := 0
+			conn := &MockConn{dbs: map[string]*MockMongoDatabase{
+				"admin": {RunFunc: func(_, cmd string) ([]byte, error) {
+					if cmd != "buildInfo" {
+						return nil, errors.New("no such cmd: " + cmd)
+					}
+
+					calls++
+
+					if tt.version == "" {
+						return nil, errors.New("buildInfo failed")
+					}
+
+					return bson.Marshal(bson.M{"version": tt.version})
+				}},
+			}}
+
+			// The version is cached per connection, failures are retried on the next poll.
+			for i := 0; i < 2; i++ {
+				if got := useCollStatsStage(context.Background(), conn); got != tt.want {
+					t.Fatalf("useCollStatsStage() = %v, want %v", got, tt.want)
+				}
+			}
+
+			if calls != tt.wantCalls {
+				t.Fatalf("buildInfo calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
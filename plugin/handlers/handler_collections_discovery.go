@@ -17,46 +17,127 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"sort"
+	"strconv"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"golang.zabbix.com/sdk/zbxerr"
 )
 
+// unauthorizedCode is the code of the Unauthorized server error.
+const unauthorizedCode = 13
+
 type colEntity struct {
-	ColName string `json:"{#COLLECTION}"`
-	DbName  string `json:"{#DBNAME}"`
+	ColName         string `json:"{#COLLECTION}"`
+	DbName          string `json:"{#DBNAME}"`
+	Type            string `json:"{#TYPE}"`
+	Capped          string `json:"{#CAPPED}"`
+	TTL             string `json:"{#TTL}"`
+	Validator       string `json:"{#VALIDATOR}"`
+	Clustered       string `json:"{#CLUSTERED}"`
+	TimeSeriesField string `json:"{#TIMESERIES_FIELD}"`
 }
 
 // CollectionsDiscoveryHandler
 // https://docs.mongodb.com/manual/reference/command/listDatabases/
-func CollectionsDiscoveryHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
-	dbs, err := s.DatabaseNames(ctx)
+// The Include and Exclude regular expressions are matched against the namespace, like "db.collection".
+// Databases which cannot contain a matching namespace, judged from the anchored literal prefixes of the patterns,
+// are not listed. Every listed database gets an equal share of the time left, so that a slow database fails
+// the discovery with its name instead of a timeout of the whole request. If the Partial parameter is true, databases
+// which cannot be listed in time or at all are skipped instead, and the discovery fails only if none of them can be
+// listed.
+// The type and options of collections are empty if the user is not allowed to list them.
+func CollectionsDiscoveryHandler(ctx context.Context, s Session, params map[string]string, _ ...string) (any, error) {
+	opts, err := newDiscoveryOptions(params)
+	if err != nil {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(err)
+	}
+
+	names, err := s.DatabaseNames(ctx)
 	if err != nil {
 		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
 	}
 
-	sort.Strings(dbs)
+	sort.Strings(names)
+
+	dbs := make([]string, 0, len(names))
+
+	for _, db := range names {
+		if opts.mayMatchCollections(db) {
+			dbs = append(dbs, db)
+		}
+	}
 
+	partial := params["Partial"] == "true"
 	lld := make([]colEntity, 0)
 
-	for _, db := range dbs {
-		var collections []string
-		collections, err = s.DB(db).CollectionNames(ctx)
+	var (
+		listed    int
+		listErr   error
+		truncated bool
+	)
+
+	for i, db := range dbs {
+		if truncated || partial && ctx.Err() != nil {
+			break
+		}
+
+		dbCtx, cancel := ShareContext(ctx, len(dbs)-i)
+		specs, err := collectionSpecs(dbCtx, s.DB(db))
 
-		sort.Strings(collections)
+		cancel()
 
 		if err != nil {
-			return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+			if !partial {
+				return nil, zbxerr.ErrorCannotFetchData.Wrap(
+					fmt.Errorf("cannot list collections of database %s: %w", db, err),
+				)
+			}
+
+			Logger.Warningf("cannot list collections of database %s: %s", db, err)
+			listErr = err
+
+			continue
 		}
 
-		for _, col := range collections {
-			lld = append(lld, colEntity{
-				ColName: col,
-				DbName:  db,
-			})
+		listed++
+
+		sort.Slice(specs, func(i, j int) bool { return specs[i].Name < specs[j].Name })
+
+		for _, spec := range specs {
+			if !opts.matchCollection(db, spec.Name) {
+				continue
+			}
+
+			if opts.full(len(lld)) {
+				truncated = true
+
+				break
+			}
+
+			lld = append(lld, newColEntity(db, spec))
 		}
 	}
 
+	if listed < len(dbs) && !truncated {
+		Logger.Warningf("collection discovery skipped %d of %d databases", len(dbs)-listed, len(dbs))
+	}
+
+	if truncated {
+		Logger.Warningf("collection discovery truncated to %d results", opts.maxResults)
+	}
+
+	if listed == 0 && len(dbs) > 0 {
+		if listErr == nil {
+			listErr = ctx.Err()
+		}
+
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(listErr)
+	}
+
 	jsonLLD, err := json.Marshal(lld)
 	if err != nil {
 		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
@@ -64,3 +145,64 @@ func CollectionsDiscoveryHandler(ctx context.Context, s Session, _ map[string]st
 
 	return string(jsonLLD), nil
 }
+
+// collectionSpecs lists the collections of the database with their type and options, or only their names
+// if the user is not allowed to list the options. Other errors are returned.
+func collectionSpecs(ctx context.Context, db Database) ([]CollectionSpec, error) {
+	specs, err := db.CollectionSpecs(ctx)
+	if err == nil || !isUnauthorized(err) {
+		return specs, err
+	}
+
+	Logger.Debugf("cannot list collection options, listing names only: %s", err)
+
+	names, err := db.CollectionNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	specs = make([]CollectionSpec, 0, len(names))
+	for _, name := range names {
+		specs = append(specs, CollectionSpec{Name: name})
+	}
+
+	return specs, nil
+}
+
+// isUnauthorized reports whether the server refused a command because the user lacks a privilege.
+func isUnauthorized(err error) bool {
+	var cmdErr mongo.CommandError
+
+	return errors.As(err, &cmdErr) && (cmdErr.Code == unauthorizedCode || cmdErr.Name == "Unauthorized")
+}
+
+func newColEntity(db string, spec CollectionSpec) colEntity {
+	e := colEntity{ColName: spec.Name, DbName: db, Type: spec.Type}
+
+	// Only names are known.
+	if spec.Type == "" {
+		return e
+	}
+
+	e.Capped = flagMacro(spec.Options["capped"] == true)
+	e.Validator = flagMacro(spec.Options["validator"] != nil)
+	e.Clustered = flagMacro(spec.Options["clusteredIndex"] != nil && spec.Options["clusteredIndex"] != false)
+
+	if ttl, ok := spec.Options["expireAfterSeconds"]; ok {
+		e.TTL = strconv.FormatInt(asInt64(ttl), 10)
+	}
+
+	if ts, ok := spec.Options["timeseries"].(bson.M); ok {
+		e.TimeSeriesField, _ = ts["timeField"].(string)
+	}
+
+	return e
+}
+
+func flagMacro(v bool) string {
+	if v {
+		return "1"
+	}
+
+	return "0"
+}
@@ -17,9 +17,14 @@ package handlers
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
 	"golang.zabbix.com/sdk/zbxerr"
 )
 
@@ -45,9 +50,8 @@ func Test_collectionsDiscoveryHandler(t *testing.T) {
 					"config": {"system.sessions"},
 				},
 			},
-			want: "[{\"{#COLLECTION}\":\"system.sessions\",\"{#DBNAME}\":\"config\"},{\"{#COLLECTION}\":" +
-				"\"startup_log\",\"{#DBNAME}\":\"local\"},{\"{#COLLECTION}\":\"col1\",\"{#DBNAME}\":\"testdb\"}," +
-				"{\"{#COLLECTION}\":\"col2\",\"{#DBNAME}\":\"testdb\"}]",
+			want: "[" + plainCollection("system.sessions", "config") + "," + plainCollection("startup_log", "local") +
+				"," + plainCollection("col1", "testdb") + "," + plainCollection("col2", "testdb") + "]",
 			wantErr: nil,
 		},
 		{
@@ -92,3 +96,240 @@ func Test_collectionsDiscoveryHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestCollectionsDiscoveryHandler_filters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    any
+		wantErr bool
+	}{
+		{
+			"+skipSystem",
+			map[string]string{"SkipSystem": "true", "Partial": "true"},
+			"[" + plainCollection("orders", "app") + "," + plainCollection("users", "app") + "," +
+				plainCollection("accounts", "crm") + "]",
+			false,
+		},
+		{
+			"+includeExclude",
+			map[string]string{"Include": `^(app|crm)\.`, "Exclude": `\.(orders|system\..*)$`},
+			"[" + plainCollection("users", "app") + "," + plainCollection("accounts", "crm") + "]",
+			false,
+		},
+		{
+			"+maxResults",
+			map[string]string{"SkipSystem": "true", "MaxResults": "2", "Partial": "true"},
+			"[" + plainCollection("orders", "app") + "," + plainCollection("users", "app") + "]",
+			false,
+		},
+		{"-failedDatabase", map[string]string{"SkipSystem": "true"}, nil, true},
+		{"-invalidMaxResults", map[string]string{"MaxResults": "-1"}, nil, true},
+		{"-invalidPattern", map[string]string{"Exclude": "("}, nil, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn := NewMockConn()
+			conn.DB("admin").C("system.version")
+			conn.DB("app").C("users")
+			conn.DB("app").C("orders")
+			conn.DB("app").C("system.views")
+			conn.DB("crm").C("accounts")
+			conn.DB("broken").C(mustFail)
+
+			got, err := CollectionsDiscoveryHandler(context.Background(), conn, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CollectionsDiscoveryHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("CollectionsDiscoveryHandler() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectionsDiscoveryHandler_specs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		specs []CollectionSpec
+		err   error
+		want  any
+	}{
+		{
+			"+view",
+			[]CollectionSpec{{Name: "active", Type: "view", Options: bson.M{"viewOn": "users"}}},
+			nil,
+			`[{"{#COLLECTION}":"active","{#DBNAME}":"app","{#TYPE}":"view","{#CAPPED}":"0","{#TTL}":"",` +
+				`"{#VALIDATOR}":"0","{#CLUSTERED}":"0","{#TIMESERIES_FIELD}":""}]`,
+		},
+		{
+			"+timeseries",
+			[]CollectionSpec{{
+				Name: "weather",
+				Type: "timeseries",
+				Options: bson.M{
+					"expireAfterSeconds": int64(86400),
+					"timeseries":         bson.M{"timeField": "ts", "granularity": "seconds"},
+				},
+			}},
+			nil,
+			`[{"{#COLLECTION}":"weather","{#DBNAME}":"app","{#TYPE}":"timeseries","{#CAPPED}":"0",` +
+				`"{#TTL}":"86400","{#VALIDATOR}":"0","{#CLUSTERED}":"0","{#TIMESERIES_FIELD}":"ts"}]`,
+		},
+		{
+			"+cappedWithValidator",
+			[]CollectionSpec{{
+				Name:    "log",
+				Type:    "collection",
+				Options: bson.M{"capped": true, "size": int32(4096), "validator": bson.M{"level": bson.M{"$gt": 0}}},
+			}},
+			nil,
+			`[{"{#COLLECTION}":"log","{#DBNAME}":"app","{#TYPE}":"collection","{#CAPPED}":"1","{#TTL}":"",` +
+				`"{#VALIDATOR}":"1","{#CLUSTERED}":"0","{#TIMESERIES_FIELD}":""}]`,
+		},
+		{
+			"+clustered",
+			[]CollectionSpec{{
+				Name:    "events",
+				Type:    "collection",
+				Options: bson.M{"clusteredIndex": bson.M{"key": bson.M{"_id": int32(1)}, "unique": true}},
+			}},
+			nil,
+			`[{"{#COLLECTION}":"events","{#DBNAME}":"app","{#TYPE}":"collection","{#CAPPED}":"0","{#TTL}":"",` +
+				`"{#VALIDATOR}":"0","{#CLUSTERED}":"1","{#TIMESERIES_FIELD}":""}]`,
+		},
+		{
+			"+namesOnlyFallback",
+			nil,
+			mongo.CommandError{Code: 13, Name: "Unauthorized", Message: "not authorized on app"},
+			`[{"{#COLLECTION}":"users","{#DBNAME}":"app","{#TYPE}":"","{#CAPPED}":"","{#TTL}":"",` +
+				`"{#VALIDATOR}":"","{#CLUSTERED}":"","{#TIMESERIES_FIELD}":""}]`,
+		},
+		{"-otherError", nil, errors.New("connection reset"), nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn := NewMockConn()
+			conn.DB("app").C("users")
+			conn.DB("app").(*MockMongoDatabase).SpecsFunc = func(context.Context) ([]CollectionSpec, error) {
+				return tt.specs, tt.err
+			}
+
+			got, err := CollectionsDiscoveryHandler(context.Background(), conn, nil)
+			if (err != nil) != (tt.want == nil) {
+				t.Fatalf("CollectionsDiscoveryHandler() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("CollectionsDiscoveryHandler() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectionsDiscoveryHandler_databases(t *testing.T) {
+	t.Parallel()
+
+	conn := NewMockConn()
+	conn.DB("app").C("users")
+	conn.DB("crm").C("accounts")
+
+	// A database which never answers must not use up the time of the others.
+	conn.DB("archive").(*MockMongoDatabase).SpecsFunc = func(ctx context.Context) ([]CollectionSpec, error) {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	// A database which always fails.
+	conn.DB("broken").(*MockMongoDatabase).SpecsFunc = func(context.Context) ([]CollectionSpec, error) {
+		return nil, errors.New("broken")
+	}
+
+	// A database excluded by its name must not be listed.
+	conn.DB("local").(*MockMongoDatabase).SpecsFunc = func(context.Context) ([]CollectionSpec, error) {
+		t.Errorf("collections of an excluded database listed")
+
+		return nil, errors.New("excluded")
+	}
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    any
+		wantErr string
+	}{
+		{
+			"+partial",
+			map[string]string{"Exclude": `^local\.`, "Partial": "true"},
+			"[" + plainCollection("users", "app") + "," + plainCollection("accounts", "crm") + "]",
+			"",
+		},
+		{
+			"+included",
+			map[string]string{"Include": `^crm\.`},
+			"[" + plainCollection("accounts", "crm") + "]",
+			"",
+		},
+		{
+			"-slowDatabase",
+			map[string]string{"Include": `^(app|archive|crm)\.`},
+			nil,
+			"cannot list collections of database archive",
+		},
+		{
+			"-failedDatabase",
+			map[string]string{"Include": `^(app|broken)\.`},
+			nil,
+			"cannot list collections of database broken",
+		},
+		{
+			"-allTimedOut",
+			map[string]string{"Include": `^(archive|local)\.`, "Exclude": `^local\.`, "Partial": "true"},
+			nil,
+			"context deadline exceeded",
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+			defer cancel()
+
+			got, err := CollectionsDiscoveryHandler(ctx, conn, tt.params)
+			if (err != nil) != (tt.wantErr != "") || err != nil && !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("CollectionsDiscoveryHandler() error = %v, wantErr %q", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("CollectionsDiscoveryHandler() = %v, want %v", got, tt.want)
+			}
+
+			if ctx.Err() != nil {
+				t.Fatalf("CollectionsDiscoveryHandler() used up the time of the request")
+			}
+		})
+	}
+}
+
+// plainCollection returns the discovery entry of a regular collection without options.
+func plainCollection(col, db string) string {
+	return fmt.Sprintf(
+		`{"{#COLLECTION}":%q,"{#DBNAME}":%q,"{#TYPE}":"collection","{#CAPPED}":"0","{#TTL}":"",`+
+			`"{#VALIDATOR}":"0","{#CLUSTERED}":"0","{#TIMESERIES_FIELD}":""}`,
+		col, db,
+	)
+}
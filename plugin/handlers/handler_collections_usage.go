@@ -17,14 +17,57 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"sort"
+	"strings"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"golang.zabbix.com/sdk/zbxerr"
 )
 
+// Modes of CollectionsUsageHandler.
+const (
+	UsageModeRaw   = "raw"
+	UsageModeRates = "rates"
+)
+
+const stateKeyTopSnapshot = "collections.usage.snapshot"
+
+// usageOperations are the operations of the top output returned as rates.
+var usageOperations = []string{"total", "readLock", "writeLock", "queries", "getmore", "insert", "update", "remove"}
+
+type usageCounter struct {
+	Time  int64 `bson:"time"`
+	Count int64 `bson:"count"`
+}
+
+type usageRate struct {
+	Time  float64 `json:"time"`
+	Count float64 `json:"count"`
+}
+
+// topSnapshot holds the cumulative top counters of every namespace at the time of a poll.
+type topSnapshot struct {
+	at       time.Time
+	counters map[string]map[string]usageCounter
+}
+
+type usageEntity struct {
+	Namespace string `json:"{#NAMESPACE}"`
+	DbName    string `json:"{#DBNAME}"`
+	ColName   string `json:"{#COLLECTION}"`
+}
+
 // CollectionsUsageHandler
 // https://docs.mongodb.com/manual/reference/command/top/index.html
-func CollectionsUsageHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
+// In the rates mode the cumulative counters are converted to per-second rates of operations and of time spent
+// in microseconds since the previous poll of the same item, per namespace. System collections are skipped.
+// The first poll of an item on a connection returns zero rates.
+func CollectionsUsageHandler(ctx context.Context, s Session, params map[string]string, _ ...string) (any, error) {
+	if params["Mode"] == UsageModeRates {
+		return collectionsUsageRates(ctx, s, params)
+	}
+
 	colUsage := &bson.M{}
 	err := s.DB("admin").Run(
 		ctx,
@@ -38,10 +81,141 @@ func CollectionsUsageHandler(ctx context.Context, s Session, _ map[string]string
 		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
 	}
 
-	jsonRes, err := json.Marshal(colUsage)
+	jsonRes, err := marshalDocument(ctx, colUsage)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+// CollectionsUsageDiscoveryHandler returns the namespaces of the top output, excluding system collections,
+// filtered by the Include and Exclude regular expressions matched against the namespace.
+func CollectionsUsageDiscoveryHandler(
+	ctx context.Context, s Session, params map[string]string, _ ...string,
+) (any, error) {
+	filter, err := newNameFilter(params["Include"], params["Exclude"])
+	if err != nil {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(err)
+	}
+
+	snapshot, err := getTopSnapshot(ctx, s)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	lld := make([]usageEntity, 0, len(snapshot.counters))
+
+	for _, ns := range sortedNamespaces(snapshot.counters) {
+		if !filter.match(ns) {
+			continue
+		}
+
+		db, col, _ := strings.Cut(ns, ".")
+		lld = append(lld, usageEntity{Namespace: ns, DbName: db, ColName: col})
+	}
+
+	jsonLLD, err := json.Marshal(lld)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonLLD), nil
+}
+
+func collectionsUsageRates(ctx context.Context, s Session, params map[string]string) (any, error) {
+	cur, err := getTopSnapshot(ctx, s)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	var prev *topSnapshot
+
+	if v, ok := s.State().Swap(itemStateKey(stateKeyTopSnapshot, params), cur); ok {
+		prev, _ = v.(*topSnapshot)
+	}
+
+	jsonRes, err := json.Marshal(usageRates(prev, cur))
 	if err != nil {
 		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
 	}
 
 	return string(jsonRes), nil
 }
+
+func getTopSnapshot(ctx context.Context, s Session) (*topSnapshot, error) {
+	var top struct {
+		Totals map[string]bson.RawValue `bson:"totals"`
+	}
+
+	err := s.DB("admin").Run(ctx, &bson.D{{Key: "top", Value: 1}}, &top)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &topSnapshot{at: time.Now(), counters: make(map[string]map[string]usageCounter)}
+
+	for ns, raw := range top.Totals {
+		// The totals also contain a note and namespaces of dropped databases without a collection part.
+		db, col, ok := strings.Cut(ns, ".")
+		if !ok || db == "" || strings.HasPrefix(col, "system.") {
+			continue
+		}
+
+		var counters map[string]usageCounter
+
+		if raw.Unmarshal(&counters) != nil {
+			continue
+		}
+
+		snapshot.counters[ns] = counters
+	}
+
+	return snapshot, nil
+}
+
+// usageRates returns the rates of every operation of the current snapshot per namespace. Rates of counters
+// which are new or were reset, for example by a server restart, are zero.
+func usageRates(prev, cur *topSnapshot) map[string]map[string]usageRate {
+	var elapsed float64
+	if prev != nil {
+		elapsed = cur.at.Sub(prev.at).Seconds()
+	}
+
+	res := make(map[string]map[string]usageRate, len(cur.counters))
+
+	for ns, counters := range cur.counters {
+		rates := make(map[string]usageRate, len(usageOperations))
+
+		for _, op := range usageOperations {
+			var rate usageRate
+
+			if elapsed > 0 {
+				if old, ok := prev.counters[ns][op]; ok {
+					c := counters[op]
+					if c.Time >= old.Time && c.Count >= old.Count {
+						rate.Time = float64(c.Time-old.Time) / elapsed
+						rate.Count = float64(c.Count-old.Count) / elapsed
+					}
+				}
+			}
+
+			rates[op] = rate
+		}
+
+		res[ns] = rates
+	}
+
+	return res
+}
+
+func sortedNamespaces(counters map[string]map[string]usageCounter) []string {
+	names := make([]string, 0, len(counters))
+	for ns := range counters {
+		names = append(names, ns)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
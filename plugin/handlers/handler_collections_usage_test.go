@@ -23,7 +23,9 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/google/go-cmp/cmp"
 	"go.mongodb.org/mongo-driver/bson"
 )
 
@@ -84,3 +86,172 @@ func Test_collectionsUsageHandler(t *testing.T) {
 		})
 	}
 }
+
+func newTopMockConn() *MockConn {
+	ops := func(n int64) bson.M {
+		return bson.M{
+			"total": bson.M{"time": n * 10, "count": n}, "readLock": bson.M{"time": n * 10, "count": n},
+			"writeLock": bson.M{"time": 0, "count": 0}, "queries": bson.M{"time": n * 10, "count": n},
+			"getmore": bson.M{"time": 0, "count": 0}, "insert": bson.M{"time": 0, "count": 0},
+			"update": bson.M{"time": 0, "count": 0}, "remove": bson.M{"time": 0, "count": 0},
+			"commands": bson.M{"time": 0, "count": 0},
+		}
+	}
+
+	conn := NewMockConn()
+	conn.DB("admin").(*MockMongoDatabase).RunFunc = func(_, cmd string) ([]byte, error) {
+		if cmd != "top" {
+			return nil, errors.New("no such cmd: " + cmd)
+		}
+
+		return bson.Marshal(bson.M{
+			"totals": bson.M{
+				"note":                 "all times in microseconds",
+				"admin.system.version": ops(1),
+				"app.users":            ops(2),
+				"app.orders":           ops(3),
+				"crm.accounts":         ops(4),
+			},
+			"ok": 1,
+		})
+	}
+
+	return conn
+}
+
+func TestCollectionsUsageHandler_rates(t *testing.T) {
+	t.Parallel()
+
+	conn := newTopMockConn()
+
+	// Items polling the same connection with different parameters keep their own snapshots.
+	items := []map[string]string{
+		{"Mode": UsageModeRates},
+		{"Mode": UsageModeRates, "Fields": "app.users"},
+	}
+
+	for i := 0; i < 2; i++ {
+		for _, params := range items {
+			got, err := CollectionsUsageHandler(context.Background(), conn, params)
+			if err != nil {
+				t.Fatalf("CollectionsUsageHandler() error = %v", err)
+			}
+
+			var rates map[string]map[string]usageRate
+
+			err = json.Unmarshal([]byte(got.(string)), &rates)
+			if err != nil {
+				t.Fatalf("CollectionsUsageHandler() returned invalid JSON: %v", err)
+			}
+
+			if len(rates) != 3 || len(rates["app.users"]) != len(usageOperations) {
+				t.Fatalf("CollectionsUsageHandler() = %s", got)
+			}
+
+			// The counters do not change between the polls.
+			if rates["app.users"]["total"] != (usageRate{}) {
+				t.Fatalf("CollectionsUsageHandler() = %s", got)
+			}
+		}
+	}
+
+	for _, params := range items {
+		if _, ok := conn.State().Load(itemStateKey(stateKeyTopSnapshot, params)); !ok {
+			t.Fatalf("CollectionsUsageHandler() did not store the snapshot of %v", params)
+		}
+	}
+}
+
+func Test_usageRates(t *testing.T) {
+	t.Parallel()
+
+	at := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	snapshot := func(seconds int, counters map[string]map[string]usageCounter) *topSnapshot {
+		return &topSnapshot{at: at.Add(time.Duration(seconds) * time.Second), counters: counters}
+	}
+
+	prev := snapshot(0, map[string]map[string]usageCounter{
+		"app.users":  {"total": {Time: 1000, Count: 10}, "insert": {Time: 500, Count: 5}},
+		"app.orders": {"total": {Time: 9000, Count: 90}},
+	})
+
+	cur := snapshot(10, map[string]map[string]usageCounter{
+		"app.users":  {"total": {Time: 3000, Count: 30}, "insert": {Time: 500, Count: 5}},
+		"app.orders": {"total": {Time: 100, Count: 1}},
+		"app.new":    {"total": {Time: 100, Count: 1}},
+	})
+
+	zero := func() map[string]usageRate {
+		res := make(map[string]usageRate)
+		for _, op := range usageOperations {
+			res[op] = usageRate{}
+		}
+
+		return res
+	}
+
+	users := zero()
+	users["total"] = usageRate{Time: 200, Count: 2}
+
+	tests := []struct {
+		name string
+		prev *topSnapshot
+		want map[string]map[string]usageRate
+	}{
+		{"+firstPoll", nil, map[string]map[string]usageRate{"app.users": zero(), "app.orders": zero(), "app.new": zero()}},
+		{"+rates", prev, map[string]map[string]usageRate{"app.users": users, "app.orders": zero(), "app.new": zero()}},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if diff := cmp.Diff(tt.want, usageRates(tt.prev, cur)); diff != "" {
+				t.Fatalf("usageRates() = %s", diff)
+			}
+		})
+	}
+}
+
+func TestCollectionsUsageDiscoveryHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    any
+		wantErr bool
+	}{
+		{
+			"+all",
+			map[string]string{},
+			`[{"{#NAMESPACE}":"app.orders","{#DBNAME}":"app","{#COLLECTION}":"orders"},` +
+				`{"{#NAMESPACE}":"app.users","{#DBNAME}":"app","{#COLLECTION}":"users"},` +
+				`{"{#NAMESPACE}":"crm.accounts","{#DBNAME}":"crm","{#COLLECTION}":"accounts"}]`,
+			false,
+		},
+		{
+			"+filtered",
+			map[string]string{"Include": `^app\.`, "Exclude": "orders"},
+			`[{"{#NAMESPACE}":"app.users","{#DBNAME}":"app","{#COLLECTION}":"users"}]`,
+			false,
+		},
+		{"-invalidPattern", map[string]string{"Include": "("}, nil, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := CollectionsUsageDiscoveryHandler(context.Background(), newTopMockConn(), tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CollectionsUsageDiscoveryHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("CollectionsUsageDiscoveryHandler() = %s", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+// DefaultAllowedCommands are read-only diagnostic commands allowed for CommandHandler and custom queries by default.
+var DefaultAllowedCommands = []string{
+	"buildInfo", "collStats", "connPoolStats", "connectionStatus", "dbStats", "getCmdLineOpts",
+	"getParameter", "hello", "hostInfo", "isMaster", "listCollections", "listCommands", "listDatabases",
+	"listIndexes", "ping", "replSetGetConfig", "replSetGetStatus", "serverStatus", "top",
+}
+
+var errEmptyCommand = errors.New("command is empty")
+
+// CommandHandler runs a command given as Extended JSON and returns the reply.
+// The command must be in the AllowedCommands setting and must not contain write stages, otherwise it is refused
+// before being sent.
+func CommandHandler(ctx context.Context, s Session, params map[string]string, _ ...string) (any, error) {
+	var cmd bson.D
+
+	err := bson.UnmarshalExtJSON([]byte(params["Command"]), false, &cmd)
+	if err != nil {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(fmt.Errorf("failed to parse command: %w", err))
+	}
+
+	if len(cmd) == 0 {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(errEmptyCommand)
+	}
+
+	err = checkReadOnly(configFrom(ctx).AllowedCommands, cmd)
+	if err != nil {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(err)
+	}
+
+	res := bson.M{}
+
+	err = s.DB(params["Database"]).Run(ctx, &cmd, &res)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	jsonRes, err := marshalDocument(ctx, res)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+func isCommandAllowed(allowed []string, name string) bool {
+	for _, c := range allowed {
+		if strings.EqualFold(c, name) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,133 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCommandHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		allowed []string
+		command string
+		want    any
+		wantErr bool
+	}{
+		{
+			"+defaultAllowlist",
+			DefaultAllowedCommands,
+			`{"dbStats": 1, "scale": 1024}`,
+			`{"db":"app","ok":1}`,
+			false,
+		},
+		{
+			"+caseInsensitive",
+			DefaultAllowedCommands,
+			`{"dbstats": 1}`,
+			`{"db":"app","ok":1}`,
+			false,
+		},
+		{
+			"-notInAllowlist",
+			DefaultAllowedCommands,
+			`{"find": "jobs"}`,
+			nil,
+			true,
+		},
+		{
+			"-writeCommand",
+			DefaultAllowedCommands,
+			`{"bulkWrite": 1, "ops": [], "nsInfo": []}`,
+			nil,
+			true,
+		},
+		{
+			"-adminCommand",
+			DefaultAllowedCommands,
+			`{"setDefaultRWConcern": 1, "defaultWriteConcern": {"w": 1}}`,
+			nil,
+			true,
+		},
+		{
+			"-writeStage",
+			[]string{"aggregate"},
+			`{"aggregate": "jobs", "pipeline": [{"$merge": "copy"}], "cursor": {}}`,
+			nil,
+			true,
+		},
+		{
+			"-malformedJSON",
+			DefaultAllowedCommands,
+			`{"dbStats": `,
+			nil,
+			true,
+		},
+		{
+			"-emptyCommand",
+			DefaultAllowedCommands,
+			`{}`,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := WithConfig(context.Background(), &Config{AllowedCommands: tt.allowed})
+
+			sent := false
+			mockSess := &MockConn{
+				dbs: map[string]*MockMongoDatabase{
+					"app": {
+						RunFunc: func(_, cmd string) ([]byte, error) {
+							sent = true
+
+							if cmd != "dbStats" && cmd != "dbstats" {
+								return nil, errors.New("no such cmd: " + cmd)
+							}
+
+							return bson.Marshal(bson.D{{Key: "db", Value: "app"}, {Key: "ok", Value: 1}})
+						},
+					},
+				},
+			}
+
+			got, err := CommandHandler(
+				ctx, mockSess, map[string]string{"Database": "app", "Command": tt.command},
+			)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CommandHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr && sent {
+				t.Fatalf("CommandHandler() sent a refused command")
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("CommandHandler() = %s", diff)
+			}
+		})
+	}
+}
@@ -37,7 +37,7 @@ type shardMap struct {
 
 // ConfigDiscoveryHandler
 // https://docs.mongodb.com/manual/reference/command/getShardMap/#dbcmd.getShardMap
-func ConfigDiscoveryHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
+func ConfigDiscoveryHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
 	var cfgServers shardMap
 	err := s.DB("admin").Run(
 		ctx,
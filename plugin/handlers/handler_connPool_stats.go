@@ -16,7 +16,6 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"golang.zabbix.com/sdk/zbxerr"
@@ -24,7 +23,7 @@ import (
 
 // ConnPoolStatsHandler
 // https://docs.mongodb.com/manual/reference/command/connPoolStats/#dbcmd.connPoolStats
-func ConnPoolStatsHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
+func ConnPoolStatsHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
 	connPoolStats := &bson.M{}
 	err := s.DB("test").Run(
 		ctx,
@@ -37,7 +36,7 @@ func ConnPoolStatsHandler(ctx context.Context, s Session, _ map[string]string) (
 		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
 	}
 
-	jsonRes, err := json.Marshal(connPoolStats)
+	jsonRes, err := marshalDocument(ctx, connPoolStats)
 	if err != nil {
 		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
 	}
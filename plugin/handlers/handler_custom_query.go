@@ -0,0 +1,270 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+const customQueryExt = ".json"
+
+// argPlaceholder matches string values like "$1" or "$2:int" that are replaced with the key arguments.
+var argPlaceholder = regexp.MustCompile(`^\$([1-9][0-9]*)(?::(string|int|double|bool|date))?$`)
+
+// writeStages are aggregation stages that modify data.
+var writeStages = map[string]bool{"$out": true, "$merge": true}
+
+var (
+	errQueriesPathNotSet = errors.New("custom queries path is not configured")
+	errInvalidQueryName  = errors.New("invalid query name")
+	errCollectionNotSet  = errors.New("collection must be set for aggregation queries")
+)
+
+// CustomQueryHandler runs a named aggregation pipeline or command loaded from the CustomQueriesPath setting.
+// A file containing an array is run as a pipeline against the collection, a file containing a document
+// is run as a command against the database, if the command is in the AllowedCommands setting.
+func CustomQueryHandler(
+	ctx context.Context, s Session, params map[string]string, extraParams ...string,
+) (any, error) {
+	query, err := loadCustomQuery(configFrom(ctx).CustomQueriesPath, params["QueryName"])
+	if err != nil {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(err)
+	}
+
+	query, err = substituteArgs(query, extraParams)
+	if err != nil {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(err)
+	}
+
+	err = checkReadOnly(configFrom(ctx).AllowedCommands, query)
+	if err != nil {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(err)
+	}
+
+	var res any
+
+	switch q := query.(type) {
+	case bson.A:
+		res, err = runCustomAggregation(ctx, s, params, q)
+	case bson.D:
+		res, err = runCustomCommand(ctx, s, params, q)
+	}
+
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	jsonRes, err := marshalDocument(ctx, res)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+func runCustomAggregation(ctx context.Context, s Session, params map[string]string, pipeline bson.A) (any, error) {
+	if params["Collection"] == "" {
+		return nil, errCollectionNotSet
+	}
+
+	q, err := s.DB(params["Database"]).C(params["Collection"]).Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []bson.M{}
+
+	err = q.Get(ctx, &docs)
+	if err != nil {
+		return nil, err
+	}
+
+	return docs, nil
+}
+
+func runCustomCommand(ctx context.Context, s Session, params map[string]string, cmd bson.D) (any, error) {
+	res := bson.M{}
+
+	err := s.DB(params["Database"]).Run(ctx, &cmd, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// loadCustomQuery reads and parses an Extended JSON query file.
+func loadCustomQuery(dir, name string) (any, error) {
+	if dir == "" {
+		return nil, errQueriesPathNotSet
+	}
+
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return nil, fmt.Errorf("%w: %q", errInvalidQueryName, name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, name+customQueryExt))
+	if err != nil {
+		return nil, err
+	}
+
+	data = []byte(strings.TrimSpace(string(data)))
+
+	if strings.HasPrefix(string(data), "[") {
+		var pipeline bson.A
+
+		err = bson.UnmarshalExtJSON(data, false, &pipeline)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse query %q: %w", name, err)
+		}
+
+		return pipeline, nil
+	}
+
+	var cmd bson.D
+
+	err = bson.UnmarshalExtJSON(data, false, &cmd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse query %q: %w", name, err)
+	}
+
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("query %q is empty", name)
+	}
+
+	return cmd, nil
+}
+
+// substituteArgs replaces placeholder values with the key arguments. Only whole values are replaced,
+// so arguments can never change the structure of a query.
+func substituteArgs(v any, args []string) (any, error) {
+	switch val := v.(type) {
+	case bson.D:
+		out := make(bson.D, 0, len(val))
+
+		for _, e := range val {
+			sub, err := substituteArgs(e.Value, args)
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, bson.E{Key: e.Key, Value: sub})
+		}
+
+		return out, nil
+	case bson.A:
+		out := make(bson.A, 0, len(val))
+
+		for _, e := range val {
+			sub, err := substituteArgs(e, args)
+			if err != nil {
+				return nil, err
+			}
+
+			out = append(out, sub)
+		}
+
+		return out, nil
+	case string:
+		m := argPlaceholder.FindStringSubmatch(val)
+		if m == nil {
+			return val, nil
+		}
+
+		idx, _ := strconv.Atoi(m[1])
+		if idx > len(args) {
+			return nil, fmt.Errorf("argument %s is not set", m[1])
+		}
+
+		return convertArg(args[idx-1], m[2])
+	default:
+		return v, nil
+	}
+}
+
+func convertArg(arg, typ string) (any, error) {
+	var (
+		res any
+		err error
+	)
+
+	switch typ {
+	case "int":
+		res, err = strconv.ParseInt(arg, 10, 64)
+	case "double":
+		res, err = strconv.ParseFloat(arg, 64)
+	case "bool":
+		res, err = strconv.ParseBool(arg)
+	case "date":
+		var sec int64
+
+		sec, err = strconv.ParseInt(arg, 10, 64)
+		res = time.Unix(sec, 0)
+	default:
+		res = arg
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert argument %q to %s: %w", arg, typ, err)
+	}
+
+	return res, nil
+}
+
+// checkReadOnly rejects commands which are not in the allowlist and pipelines containing write stages
+// at any depth.
+func checkReadOnly(allowed []string, query any) error {
+	if cmd, ok := query.(bson.D); ok && !isCommandAllowed(allowed, cmd[0].Key) {
+		return fmt.Errorf("command %q is not allowed", cmd[0].Key)
+	}
+
+	return checkWriteStages(query)
+}
+
+func checkWriteStages(v any) error {
+	switch val := v.(type) {
+	case bson.D:
+		for _, e := range val {
+			if writeStages[e.Key] {
+				return fmt.Errorf("stage %q is not read-only", e.Key)
+			}
+
+			err := checkWriteStages(e.Value)
+			if err != nil {
+				return err
+			}
+		}
+	case bson.A:
+		for _, e := range val {
+			err := checkWriteStages(e)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,193 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestCustomQueryHandler(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"backlog.json":    `[{"$match": {"status": "pending", "queue": "$1", "attempts": {"$gte": "$2:int"}}}, {"$count": "n"}]`,
+		"stats.json":      `{"dbStats": 1, "scale": "$1:int"}`,
+		"export.json":     `[{"$match": {}}, {"$facet": {"a": [{"$out": "copy"}]}}]`,
+		"drop.json":       `{"drop": "jobs"}`,
+		"bulkwrite.json":  `{"bulkWrite": 1, "ops": [], "nsInfo": []}`,
+		"abort.json":      `{"abortTransaction": 1}`,
+		"broken.json":     `[{"$match": `,
+		"injection.json":  `[{"$match": {"queue": "$1"}}]`,
+		"noargument.json": `{"dbStats": 1, "scale": "$3"}`,
+	}
+
+	for name, data := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := WithConfig(context.Background(), &Config{CustomQueriesPath: dir, AllowedCommands: DefaultAllowedCommands})
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		args    []string
+		want    any
+		wantErr bool
+	}{
+		{
+			"+aggregation",
+			map[string]string{"Database": "app", "Collection": "jobs", "QueryName": "backlog"},
+			[]string{"emails", "3"},
+			`[{"n":42}]`,
+			false,
+		},
+		{
+			"+command",
+			map[string]string{"Database": "app", "QueryName": "stats"},
+			[]string{"1024"},
+			`{"ok":1}`,
+			false,
+		},
+		{
+			"+argumentIsNotParsed",
+			map[string]string{"Database": "app", "Collection": "jobs", "QueryName": "injection"},
+			[]string{`{"$ne": null}`},
+			`[]`,
+			false,
+		},
+		{
+			"-writeStage",
+			map[string]string{"Database": "app", "Collection": "jobs", "QueryName": "export"},
+			nil,
+			nil,
+			true,
+		},
+		{
+			"-writeCommand",
+			map[string]string{"Database": "app", "QueryName": "drop"},
+			nil,
+			nil,
+			true,
+		},
+		{
+			"-commandNotAllowed",
+			map[string]string{"Database": "app", "QueryName": "bulkwrite"},
+			nil,
+			nil,
+			true,
+		},
+		{
+			"-transactionCommand",
+			map[string]string{"Database": "admin", "QueryName": "abort"},
+			nil,
+			nil,
+			true,
+		},
+		{
+			"-malformedFile",
+			map[string]string{"Database": "app", "QueryName": "broken"},
+			nil,
+			nil,
+			true,
+		},
+		{
+			"-pathTraversal",
+			map[string]string{"Database": "app", "QueryName": "../backlog"},
+			nil,
+			nil,
+			true,
+		},
+		{
+			"-unknownQuery",
+			map[string]string{"Database": "app", "QueryName": "missing"},
+			nil,
+			nil,
+			true,
+		},
+		{
+			"-missingArgument",
+			map[string]string{"Database": "app", "QueryName": "noargument"},
+			[]string{"1"},
+			nil,
+			true,
+		},
+		{
+			"-badArgumentType",
+			map[string]string{"Database": "app", "Collection": "jobs", "QueryName": "backlog"},
+			[]string{"emails", "three"},
+			nil,
+			true,
+		},
+		{
+			"-aggregationWithoutCollection",
+			map[string]string{"Database": "app", "QueryName": "backlog"},
+			[]string{"emails", "3"},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockSess := NewMockConn()
+
+			pipeline := bson.A{
+				bson.D{{Key: "$match", Value: bson.D{
+					{Key: "status", Value: "pending"},
+					{Key: "queue", Value: "emails"},
+					{Key: "attempts", Value: bson.D{{Key: "$gte", Value: int64(3)}}},
+				}}},
+				bson.D{{Key: "$count", Value: "n"}},
+			}
+
+			q, err := mockSess.DB("app").C("jobs").Aggregate(context.Background(), pipeline)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			q.(*MockMongoQuery).DataFunc = func() ([]byte, error) {
+				return bson.Marshal(bson.D{{Key: "0", Value: bson.M{"n": 42}}})
+			}
+
+			injected := bson.A{bson.D{{Key: "$match", Value: bson.D{{Key: "queue", Value: `{"$ne": null}`}}}}}
+
+			q, err = mockSess.DB("app").C("jobs").Aggregate(context.Background(), injected)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			q.(*MockMongoQuery).DataFunc = func() ([]byte, error) {
+				return bson.Marshal(bson.D{})
+			}
+
+			got, err := CustomQueryHandler(ctx, mockSess, tt.params, tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CustomQueryHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("CustomQueryHandler() = %s", diff)
+			}
+		})
+	}
+}
@@ -16,7 +16,6 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"golang.zabbix.com/sdk/zbxerr"
@@ -24,7 +23,7 @@ import (
 
 // DatabaseStatsHandler
 // https://docs.mongodb.com/manual/reference/command/dbStats/index.html
-func DatabaseStatsHandler(ctx context.Context, s Session, params map[string]string) (any, error) {
+func DatabaseStatsHandler(ctx context.Context, s Session, params map[string]string, _ ...string) (any, error) {
 	dbStats := &bson.M{}
 	err := s.DB(params["Database"]).Run(
 		ctx,
@@ -38,7 +37,7 @@ func DatabaseStatsHandler(ctx context.Context, s Session, params map[string]stri
 		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
 	}
 
-	jsonRes, err := json.Marshal(dbStats)
+	jsonRes, err := marshalDocument(ctx, dbStats)
 	if err != nil {
 		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
 	}
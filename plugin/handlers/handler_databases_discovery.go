@@ -28,7 +28,13 @@ type dbEntity struct {
 
 // DatabasesDiscoveryHandler
 // https://docs.mongodb.com/manual/reference/command/listDatabases/
-func DatabasesDiscoveryHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
+// The Include and Exclude regular expressions are matched against the database name.
+func DatabasesDiscoveryHandler(ctx context.Context, s Session, params map[string]string, _ ...string) (any, error) {
+	opts, err := newDiscoveryOptions(params)
+	if err != nil {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(err)
+	}
+
 	dbs, err := s.DatabaseNames(ctx)
 	if err != nil {
 		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
@@ -39,6 +45,16 @@ func DatabasesDiscoveryHandler(ctx context.Context, s Session, _ map[string]stri
 	lld := make([]dbEntity, 0)
 
 	for _, db := range dbs {
+		if !opts.matchDatabase(db) || !opts.filter.match(db) {
+			continue
+		}
+
+		if opts.full(len(lld)) {
+			Logger.Warningf("database discovery truncated to %d results", opts.maxResults)
+
+			break
+		}
+
 		lld = append(lld, dbEntity{DBName: db})
 	}
 
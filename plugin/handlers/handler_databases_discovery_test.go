@@ -74,3 +74,41 @@ func Test_databasesDiscoveryHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestDatabasesDiscoveryHandler_filters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		want    any
+		wantErr bool
+	}{
+		{"+all", nil, `[{"{#DBNAME}":"admin"},{"{#DBNAME}":"app"},{"{#DBNAME}":"crm"},{"{#DBNAME}":"local"}]`, false},
+		{"+skipSystem", map[string]string{"SkipSystem": "true"}, `[{"{#DBNAME}":"app"},{"{#DBNAME}":"crm"}]`, false},
+		{"+include", map[string]string{"Include": "^a"}, `[{"{#DBNAME}":"admin"},{"{#DBNAME}":"app"}]`, false},
+		{"+exclude", map[string]string{"Exclude": "^a"}, `[{"{#DBNAME}":"crm"},{"{#DBNAME}":"local"}]`, false},
+		{"+maxResults", map[string]string{"MaxResults": "1"}, `[{"{#DBNAME}":"admin"}]`, false},
+		{"-invalidMaxResults", map[string]string{"MaxResults": "x"}, nil, true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn := NewMockConn()
+			for _, db := range []string{"local", "crm", "app", "admin"} {
+				conn.DB(db)
+			}
+
+			got, err := DatabasesDiscoveryHandler(context.Background(), conn, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DatabasesDiscoveryHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("DatabasesDiscoveryHandler() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+const (
+	defaultStorageEngine = "wiredTiger"
+
+	// WiredTiger uses 50% of (RAM - 1 GB) or 256 MB, whichever is larger, if the cache size is not set.
+	wtMinCacheMB      = 256
+	wtReservedRAMMB   = 1024
+	wtCacheRatioOfRAM = 0.5
+
+	mbInGB  = 1024
+	percent = 100
+)
+
+type hostInfo struct {
+	System struct {
+		Hostname    string `bson:"hostname"`
+		CPUArch     string `bson:"cpuArch"`
+		NumCores    int64  `bson:"numCores"`
+		MemSizeMB   int64  `bson:"memSizeMB"`
+		MemLimitMB  int64  `bson:"memLimitMB"`
+		NumaEnabled bool   `bson:"numaEnabled"`
+	} `bson:"system"`
+	OS struct {
+		Type    string `bson:"type"`
+		Name    string `bson:"name"`
+		Version string `bson:"version"`
+	} `bson:"os"`
+}
+
+type cmdLineOpts struct {
+	Parsed struct {
+		Net struct {
+			BindIP string `bson:"bindIp"`
+			Port   int64  `bson:"port"`
+			TLS    struct {
+				Mode string `bson:"mode"`
+			} `bson:"tls"`
+		} `bson:"net"`
+		Storage struct {
+			DBPath     string `bson:"dbPath"`
+			Engine     string `bson:"engine"`
+			WiredTiger struct {
+				EngineConfig struct {
+					CacheSizeGB float64 `bson:"cacheSizeGB"`
+				} `bson:"engineConfig"`
+			} `bson:"wiredTiger"`
+		} `bson:"storage"`
+		Replication struct {
+			ReplSetName string `bson:"replSetName"`
+		} `bson:"replication"`
+		Sharding struct {
+			ClusterRole string `bson:"clusterRole"`
+		} `bson:"sharding"`
+		Security struct {
+			Authorization   string `bson:"authorization"`
+			KeyFile         string `bson:"keyFile"`
+			ClusterAuthMode string `bson:"clusterAuthMode"`
+		} `bson:"security"`
+	} `bson:"parsed"`
+}
+
+type hostOS struct {
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type hostSecurity struct {
+	Authorization   string `json:"authorization"`
+	KeyFile         bool   `json:"keyFile"`
+	ClusterAuthMode string `json:"clusterAuthMode"`
+	TLSMode         string `json:"tlsMode"`
+}
+
+type hostInfoResult struct {
+	Hostname            string       `json:"hostname"`
+	CPUArch             string       `json:"cpuArch"`
+	NumCores            int64        `json:"numCores"`
+	MemSizeMB           int64        `json:"memSizeMB"`
+	MemLimitMB          int64        `json:"memLimitMB"`
+	NumaEnabled         bool         `json:"numaEnabled"`
+	OS                  hostOS       `json:"os"`
+	DBPath              string       `json:"dbPath"`
+	StorageEngine       string       `json:"storageEngine"`
+	CacheSizeGB         float64      `json:"cacheSizeGB"`
+	CacheSizeConfigured bool         `json:"cacheSizeConfigured"`
+	CacheSizePctOfRAM   float64      `json:"cacheSizePctOfRAM"`
+	BindIP              string       `json:"bindIp"`
+	Port                int64        `json:"port"`
+	ReplSetName         string       `json:"replSetName"`
+	ClusterRole         string       `json:"clusterRole"`
+	Security            hostSecurity `json:"security"`
+	ContainerLimited    bool         `json:"containerLimited"`
+}
+
+// HostInfoHandler combines 'hostInfo' and 'getCmdLineOpts' into a single description of the host and
+// the effective server configuration.
+// https://www.mongodb.com/docs/manual/reference/command/hostInfo/
+// https://www.mongodb.com/docs/manual/reference/command/getCmdLineOpts/
+func HostInfoHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
+	var host hostInfo
+
+	err := s.DB("admin").Run(ctx, &bson.D{{Key: "hostInfo", Value: 1}}, &host)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	var opts cmdLineOpts
+
+	err = s.DB("admin").Run(ctx, &bson.D{{Key: "getCmdLineOpts", Value: 1}}, &opts)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	jsonRes, err := json.Marshal(newHostInfoResult(&host, &opts))
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+func newHostInfoResult(host *hostInfo, opts *cmdLineOpts) hostInfoResult {
+	parsed := &opts.Parsed
+
+	res := hostInfoResult{
+		Hostname:    host.System.Hostname,
+		CPUArch:     host.System.CPUArch,
+		NumCores:    host.System.NumCores,
+		MemSizeMB:   host.System.MemSizeMB,
+		MemLimitMB:  host.System.MemLimitMB,
+		NumaEnabled: host.System.NumaEnabled,
+		OS: hostOS{
+			Type:    host.OS.Type,
+			Name:    host.OS.Name,
+			Version: host.OS.Version,
+		},
+		DBPath:        parsed.Storage.DBPath,
+		StorageEngine: parsed.Storage.Engine,
+		BindIP:        parsed.Net.BindIP,
+		Port:          parsed.Net.Port,
+		ReplSetName:   parsed.Replication.ReplSetName,
+		ClusterRole:   parsed.Sharding.ClusterRole,
+		Security: hostSecurity{
+			Authorization:   parsed.Security.Authorization,
+			KeyFile:         parsed.Security.KeyFile != "",
+			ClusterAuthMode: parsed.Security.ClusterAuthMode,
+			TLSMode:         parsed.Net.TLS.Mode,
+		},
+	}
+
+	if res.StorageEngine == "" {
+		res.StorageEngine = defaultStorageEngine
+	}
+
+	// memLimitMB reflects cgroup limits, older servers do not report it.
+	if res.MemLimitMB == 0 {
+		res.MemLimitMB = res.MemSizeMB
+	}
+
+	res.ContainerLimited = res.MemLimitMB < res.MemSizeMB
+
+	if res.StorageEngine != defaultStorageEngine {
+		return res
+	}
+
+	res.CacheSizeGB = parsed.Storage.WiredTiger.EngineConfig.CacheSizeGB
+	res.CacheSizeConfigured = res.CacheSizeGB > 0
+
+	if !res.CacheSizeConfigured {
+		cacheMB := math.Max(wtCacheRatioOfRAM*float64(res.MemLimitMB-wtReservedRAMMB), wtMinCacheMB)
+		res.CacheSizeGB = cacheMB / mbInGB
+	}
+
+	if res.MemLimitMB > 0 {
+		res.CacheSizePctOfRAM = math.Round(res.CacheSizeGB*mbInGB/float64(res.MemLimitMB)*percent*percent) / percent
+	}
+
+	return res
+}
@@ -0,0 +1,127 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestHostInfoHandler(t *testing.T) {
+	t.Parallel()
+
+	hostInfoResp := bson.M{
+		"system": bson.M{
+			"hostname":    "db1:27017",
+			"cpuArch":     "x86_64",
+			"numCores":    int32(8),
+			"memSizeMB":   int64(32768),
+			"memLimitMB":  int64(8192),
+			"numaEnabled": false,
+		},
+		"os": bson.M{"type": "Linux", "name": "Ubuntu", "version": "22.04"},
+		"ok": 1,
+	}
+
+	tests := []struct {
+		name    string
+		opts    bson.M
+		err     error
+		want    any
+		wantErr bool
+	}{
+		{
+			"+defaultCacheInContainer",
+			bson.M{
+				"parsed": bson.M{
+					"net":         bson.M{"bindIp": "0.0.0.0", "port": int32(27017)},
+					"storage":     bson.M{"dbPath": "/data/db"},
+					"replication": bson.M{"replSetName": "rs0"},
+					"security":    bson.M{"authorization": "enabled", "keyFile": "/etc/mongo.key"},
+				},
+			},
+			nil,
+			`{"hostname":"db1:27017","cpuArch":"x86_64","numCores":8,"memSizeMB":32768,"memLimitMB":8192,` +
+				`"numaEnabled":false,"os":{"type":"Linux","name":"Ubuntu","version":"22.04"},"dbPath":"/data/db",` +
+				`"storageEngine":"wiredTiger","cacheSizeGB":3.5,"cacheSizeConfigured":false,"cacheSizePctOfRAM":43.75,` +
+				`"bindIp":"0.0.0.0","port":27017,"replSetName":"rs0","clusterRole":"","security":{"authorization":` +
+				`"enabled","keyFile":true,"clusterAuthMode":"","tlsMode":""},"containerLimited":true}`,
+			false,
+		},
+		{
+			"+configuredCache",
+			bson.M{
+				"parsed": bson.M{
+					"storage": bson.M{
+						"dbPath":     "/data/db",
+						"engine":     "wiredTiger",
+						"wiredTiger": bson.M{"engineConfig": bson.M{"cacheSizeGB": 2}},
+					},
+				},
+			},
+			nil,
+			`{"hostname":"db1:27017","cpuArch":"x86_64","numCores":8,"memSizeMB":32768,"memLimitMB":8192,` +
+				`"numaEnabled":false,"os":{"type":"Linux","name":"Ubuntu","version":"22.04"},"dbPath":"/data/db",` +
+				`"storageEngine":"wiredTiger","cacheSizeGB":2,"cacheSizeConfigured":true,"cacheSizePctOfRAM":25,` +
+				`"bindIp":"","port":0,"replSetName":"","clusterRole":"","security":{"authorization":` +
+				`"","keyFile":false,"clusterAuthMode":"","tlsMode":""},"containerLimited":true}`,
+			false,
+		},
+		{
+			"-commandErr",
+			nil,
+			errors.New("fail"),
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockSess := &MockConn{
+				dbs: map[string]*MockMongoDatabase{
+					"admin": {
+						RunFunc: func(_, cmd string) ([]byte, error) {
+							if cmd == "hostInfo" {
+								return bson.Marshal(hostInfoResp)
+							}
+
+							if tt.err != nil {
+								return nil, tt.err
+							}
+
+							return bson.Marshal(tt.opts)
+						},
+					},
+				},
+			}
+
+			got, err := HostInfoHandler(context.Background(), mockSess, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HostInfoHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("HostInfoHandler() = %s", diff)
+			}
+		})
+	}
+}
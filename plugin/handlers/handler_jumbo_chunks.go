@@ -23,7 +23,7 @@ import (
 
 // JumboChunksHandler
 // https://docs.mongodb.com/manual/core/sharding-data-partitioning/#indivisible-jumbo-chunks
-func JumboChunksHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
+func JumboChunksHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
 	q, err := s.DB("config").C("chunks").Find(ctx, bson.M{"jumbo": true})
 	if err != nil {
 		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
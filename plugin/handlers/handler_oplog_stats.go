@@ -29,7 +29,7 @@ import (
 
 // OplogStatsHandler
 // https://docs.mongodb.com/manual/reference/method/db.getReplicationInfo/index.html
-func OplogStatsHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
+func OplogStatsHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
 	var (
 		err             error
 		firstTs, lastTs int
@@ -136,7 +136,7 @@ func Test_oplogStatsHandler(t *testing.T) {
 			t.Parallel()
 
 			mockSess := &MockConn{
-				map[string]*MockMongoDatabase{
+				dbs: map[string]*MockMongoDatabase{
 					"local": {collections: tt.fields.collections},
 				},
 			}
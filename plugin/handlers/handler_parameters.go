@@ -0,0 +1,157 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"reflect"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+// replyServiceKeys are added by the server to every command reply and are not part of the result.
+var replyServiceKeys = []string{"ok", "$clusterTime", "operationTime", "$gleStats", "$configServerState"}
+
+type paramMismatch struct {
+	Name     string `json:"name"`
+	Expected any    `json:"expected"`
+	Actual   any    `json:"actual"`
+}
+
+type paramsDrift struct {
+	Expected   string          `json:"expected"`
+	Drift      int             `json:"drift"`
+	Mismatches []paramMismatch `json:"mismatches"`
+}
+
+// ParametersHandler returns values of the given server parameters, or all of them if none are given.
+// https://www.mongodb.com/docs/manual/reference/command/getParameter/
+func ParametersHandler(ctx context.Context, s Session, _ map[string]string, names ...string) (any, error) {
+	params, err := getParameters(ctx, s, names)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	jsonRes, err := marshalDocument(ctx, params)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+// ParametersDriftHandler compares server parameters with expected values from a JSON file
+// in the BaselinesPath directory.
+// https://www.mongodb.com/docs/manual/reference/command/getParameter/
+func ParametersDriftHandler(ctx context.Context, s Session, params map[string]string, _ ...string) (any, error) {
+	path, err := baselinePath(configFrom(ctx).BaselinesPath, params["ExpectedFile"])
+	if err != nil {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, zbxerr.New("failed to read expected values file").Wrap(err)
+	}
+
+	var expected map[string]any
+
+	err = json.Unmarshal(data, &expected)
+	if err != nil {
+		return nil, zbxerr.New("failed to parse expected values file").Wrap(err)
+	}
+
+	names := make([]string, 0, len(expected))
+	for name := range expected {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	res := paramsDrift{Expected: params["ExpectedFile"], Mismatches: []paramMismatch{}}
+
+	if len(names) > 0 {
+		actual, err := getParameters(ctx, s, names)
+		if err != nil {
+			return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+		}
+
+		normalized, err := jsonDocument(actual)
+		if err != nil {
+			return nil, zbxerr.ErrorCannotParseResult.Wrap(err)
+		}
+
+		for _, name := range names {
+			if !paramMatches(expected[name], normalized[name]) {
+				res.Mismatches = append(
+					res.Mismatches,
+					paramMismatch{Name: name, Expected: expected[name], Actual: normalized[name]},
+				)
+			}
+		}
+	}
+
+	res.Drift = len(res.Mismatches)
+
+	jsonRes, err := json.Marshal(res)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+func getParameters(ctx context.Context, s Session, names []string) (bson.M, error) {
+	cmd := bson.D{{Key: "getParameter", Value: "*"}}
+
+	if len(names) > 0 {
+		cmd = bson.D{{Key: "getParameter", Value: 1}}
+
+		for _, name := range names {
+			cmd = append(cmd, bson.E{Key: name, Value: 1})
+		}
+	}
+
+	res := bson.M{}
+
+	err := s.DB("admin").Run(ctx, &cmd, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range replyServiceKeys {
+		delete(res, k)
+	}
+
+	return res, nil
+}
+
+// paramMatches compares an expected value with the actual one. A scalar expected value is compared with
+// the version field of document parameters, such as featureCompatibilityVersion.
+func paramMatches(expected, actual any) bool {
+	if doc, ok := actual.(map[string]any); ok {
+		if _, isDoc := expected.(map[string]any); !isDoc {
+			if version, found := doc["version"]; found {
+				return reflect.DeepEqual(expected, version)
+			}
+		}
+	}
+
+	return reflect.DeepEqual(expected, actual)
+}
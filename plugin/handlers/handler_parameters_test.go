@@ -0,0 +1,150 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func newParametersMock(t *testing.T) *MockConn {
+	t.Helper()
+
+	return &MockConn{
+		dbs: map[string]*MockMongoDatabase{
+			"admin": {
+				RunFunc: func(_, _ string) ([]byte, error) {
+					return bson.Marshal(bson.M{
+						"featureCompatibilityVersion":       bson.M{"version": "5.0"},
+						"transactionLifetimeLimitSeconds":   int32(60),
+						"maxIndexBuildMemoryUsageMegabytes": int32(200),
+						"ok":                                1,
+						"operationTime":                     int64(1),
+					})
+				},
+			},
+		},
+	}
+}
+
+func TestParametersHandler(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParametersHandler(
+		context.Background(), newParametersMock(t), nil, "featureCompatibilityVersion",
+	)
+	if err != nil {
+		t.Fatalf("ParametersHandler() error = %v", err)
+	}
+
+	want := `{"featureCompatibilityVersion":{"version":"5.0"},"maxIndexBuildMemoryUsageMegabytes":200,` +
+		`"transactionLifetimeLimitSeconds":60}`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("ParametersHandler() = %s", diff)
+	}
+}
+
+func TestParametersDriftHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+		want     string
+		wantErr  bool
+	}{
+		{
+			"+match",
+			"expected.json",
+			`{"featureCompatibilityVersion": "5.0", "transactionLifetimeLimitSeconds": 60}`,
+			`{"expected":"%s","drift":0,"mismatches":[]}`,
+			false,
+		},
+		{
+			"+fcvLagsBehind",
+			"expected.json",
+			`{"featureCompatibilityVersion": "6.0", "maxIndexBuildMemoryUsageMegabytes": 200, ` +
+				`"transactionLifetimeLimitSeconds": 30}`,
+			`{"expected":"%s","drift":2,"mismatches":[{"name":"featureCompatibilityVersion","expected":"6.0",` +
+				`"actual":{"version":"5.0"}},{"name":"transactionLifetimeLimitSeconds","expected":30,"actual":60}]}`,
+			false,
+		},
+		{
+			"-malformedFile",
+			"expected.json",
+			`{`,
+			"",
+			true,
+		},
+		{
+			"-outsideBaselinesPath",
+			"../expected.json",
+			`{"featureCompatibilityVersion": "5.0"}`,
+			"",
+			true,
+		},
+		{
+			"-absolutePath",
+			"/etc/passwd",
+			`{"featureCompatibilityVersion": "5.0"}`,
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := filepath.Join(t.TempDir(), "baselines")
+
+			err := os.Mkdir(dir, 0o700)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Written both inside and next to the baselines directory, only the first one may be read.
+			for _, path := range []string{filepath.Join(dir, "expected.json"), filepath.Join(dir, "..", "expected.json")} {
+				err = os.WriteFile(path, []byte(tt.expected), 0o600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := ParametersDriftHandler(
+				WithConfig(context.Background(), &Config{BaselinesPath: dir}), newParametersMock(t),
+				map[string]string{"ExpectedFile": tt.file},
+			)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParametersDriftHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if diff := cmp.Diff(strings.Replace(tt.want, "%s", tt.file, 1), got); diff != "" {
+				t.Fatalf("ParametersDriftHandler() = %s", diff)
+			}
+		})
+	}
+}
@@ -18,7 +18,7 @@ import "context"
 
 // PingHandler executes 'ping' command and returns pingOk if a connection is alive or pingFailed otherwise.
 // https://docs.mongodb.com/manual/reference/command/ping/index.html
-func PingHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
+func PingHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
 	if err := s.Ping(ctx); err != nil {
 		Logger.Debugf("ping failed, %s", err.Error())
 
@@ -16,7 +16,6 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"golang.zabbix.com/sdk/zbxerr"
@@ -24,7 +23,21 @@ import (
 
 // ReplSetConfigHandler
 // https://docs.mongodb.com/manual/reference/command/replSetGetConfig/index.html
-func ReplSetConfigHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
+func ReplSetConfigHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
+	replSetGetConfig, err := getReplSetConfig(ctx, s)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	jsonRes, err := marshalDocument(ctx, replSetGetConfig)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+func getReplSetConfig(ctx context.Context, s Session) (*bson.M, error) {
 	replSetGetConfig := &bson.M{}
 	err := s.DB("admin").Run(
 		ctx,
@@ -40,15 +53,9 @@ func ReplSetConfigHandler(ctx context.Context, s Session, _ map[string]string) (
 		},
 		replSetGetConfig,
 	)
-
 	if err != nil {
-		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+		return nil, err
 	}
 
-	jsonRes, err := json.Marshal(replSetGetConfig)
-	if err != nil {
-		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
-	}
-
-	return string(jsonRes), nil
+	return replSetGetConfig, nil
 }
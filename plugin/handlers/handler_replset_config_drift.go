@@ -0,0 +1,350 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+const (
+	// DriftModeCompare compares the live configuration with an existing baseline file.
+	DriftModeCompare = "compare"
+	// DriftModeSnapshot writes the live configuration as a baseline if the file does not exist yet.
+	DriftModeSnapshot = "snapshot"
+
+	baselineFileMode = 0o644
+)
+
+// rwConcernKey is the key of the cluster-wide read and write concern defaults in a normalized configuration.
+const rwConcernKey = "defaultRWConcern"
+
+var (
+	errBaselinesPathNotSet = errors.New("baselines path is not configured")
+	errInvalidBaselineName = errors.New("invalid baseline file name")
+)
+
+// replSetConfigIgnoredKeys are changed by every reconfig and do not describe the configuration itself.
+var replSetConfigIgnoredKeys = []string{"version", "term"}
+
+// rwConcernKeys are the fields of the getDefaultRWConcern reply that describe the defaults, the others are
+// timestamps and sources of the values.
+var rwConcernKeys = []string{"defaultWriteConcern", "defaultReadConcern"}
+
+type configChange struct {
+	Path     string `json:"path"`
+	Expected any    `json:"expected"`
+	Actual   any    `json:"actual"`
+}
+
+type configDrift struct {
+	Baseline string         `json:"baseline"`
+	Snapshot bool           `json:"snapshot"`
+	Drift    int            `json:"drift"`
+	Changes  []configChange `json:"changes"`
+}
+
+// ReplSetConfigDriftHandler compares the replica set configuration and the cluster-wide read and write concern
+// defaults with a baseline JSON or YAML file in the BaselinesPath directory.
+// https://docs.mongodb.com/manual/reference/command/replSetGetConfig/index.html
+// https://www.mongodb.com/docs/manual/reference/command/getDefaultRWConcern/
+func ReplSetConfigDriftHandler(ctx context.Context, s Session, params map[string]string, _ ...string) (any, error) {
+	path, err := baselinePath(configFrom(ctx).BaselinesPath, params["BaselineFile"])
+	if err != nil {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(err)
+	}
+
+	replSetGetConfig, err := getReplSetConfig(ctx, s)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	actual, err := normalizeReplSetConfig(replSetGetConfig)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotParseResult.Wrap(err)
+	}
+
+	rwConcern, err := getDefaultRWConcern(ctx, s)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	if rwConcern != nil {
+		actual[rwConcernKey], err = normalizeRWConcern(rwConcern)
+		if err != nil {
+			return nil, zbxerr.ErrorCannotParseResult.Wrap(err)
+		}
+	}
+
+	res := configDrift{Baseline: params["BaselineFile"], Changes: []configChange{}}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) || params["Mode"] != DriftModeSnapshot {
+			return nil, zbxerr.New("failed to read baseline file").Wrap(err)
+		}
+
+		err = writeBaseline(path, actual)
+		if err != nil {
+			return nil, err
+		}
+
+		res.Snapshot = true
+
+		return marshalDrift(res)
+	}
+
+	baseline, err := parseBaseline(path, data)
+	if err != nil {
+		return nil, zbxerr.New("failed to parse baseline file").Wrap(err)
+	}
+
+	expected, err := normalizeReplSetConfig(baseline)
+	if err != nil {
+		return nil, zbxerr.New("failed to parse baseline file").Wrap(err)
+	}
+
+	if rwConcern, ok := baseline[rwConcernKey]; ok {
+		expected[rwConcernKey], err = normalizeRWConcern(rwConcern)
+		if err != nil {
+			return nil, zbxerr.New("failed to parse baseline file").Wrap(err)
+		}
+	}
+
+	diffConfig("", expected, actual, &res.Changes)
+	res.Drift = len(res.Changes)
+
+	return marshalDrift(res)
+}
+
+// normalizeReplSetConfig converts a replSetGetConfig result or a bare config document to a form
+// suitable for comparison: BSON types are turned to their JSON representation, volatile keys are dropped
+// and members are keyed by their _id, so reordering them is not reported as a change.
+func normalizeReplSetConfig(raw any) (map[string]any, error) {
+	cfg, err := jsonDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if inner, ok := cfg["config"].(map[string]any); ok {
+		cfg = inner
+	}
+
+	for _, k := range replSetConfigIgnoredKeys {
+		delete(cfg, k)
+	}
+
+	if members, ok := cfg["members"].([]any); ok {
+		byID := make(map[string]any, len(members))
+
+		for _, m := range members {
+			member, isMap := m.(map[string]any)
+			if !isMap {
+				return nil, errUnknownStructure
+			}
+
+			id, isNum := member["_id"].(float64)
+			if !isNum {
+				return nil, errUnknownStructure
+			}
+
+			byID[strconv.FormatFloat(id, 'f', -1, 64)] = member
+		}
+
+		cfg["members"] = byID
+	}
+
+	return cfg, nil
+}
+
+// getDefaultRWConcern returns the cluster-wide read and write concern defaults, or nil if the server is older
+// than MongoDB 4.4 and does not support them.
+func getDefaultRWConcern(ctx context.Context, s Session) (bson.M, error) {
+	rwConcern := bson.M{}
+
+	err := s.DB("admin").Run(ctx, &bson.D{{Key: "getDefaultRWConcern", Value: 1}}, &rwConcern)
+	if err != nil {
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Name == "CommandNotFound" {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return rwConcern, nil
+}
+
+// normalizeRWConcern keeps only the defaults of a getDefaultRWConcern reply or a baseline,
+// defaults that are not set are null.
+func normalizeRWConcern(raw any) (map[string]any, error) {
+	doc, err := jsonDocument(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]any, len(rwConcernKeys))
+	for _, k := range rwConcernKeys {
+		res[k] = doc[k]
+	}
+
+	return res, nil
+}
+
+// jsonDocument converts a document to its JSON representation, so it can be compared with values
+// read from JSON files.
+func jsonDocument(v any) (map[string]any, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc map[string]any
+
+	err = json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func diffConfig(path string, expected, actual any, changes *[]configChange) {
+	expMap, expOk := expected.(map[string]any)
+	actMap, actOk := actual.(map[string]any)
+
+	if !expOk || !actOk {
+		if !reflect.DeepEqual(expected, actual) {
+			*changes = append(*changes, configChange{Path: path, Expected: expected, Actual: actual})
+		}
+
+		return
+	}
+
+	keys := make([]string, 0, len(expMap)+len(actMap))
+
+	for k := range expMap {
+		keys = append(keys, k)
+	}
+
+	for k := range actMap {
+		if _, ok := expMap[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		childPath := k
+		if path != "" {
+			childPath = path + "." + k
+		}
+
+		diffConfig(childPath, expMap[k], actMap[k], changes)
+	}
+}
+
+// baselinePath returns the path of a baseline file in the directory. Only names inside the directory are
+// accepted, so item keys cannot read or write other files on the agent host.
+func baselinePath(dir, name string) (string, error) {
+	if dir == "" {
+		return "", errBaselinesPathNotSet
+	}
+
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("%w: %q", errInvalidBaselineName, name)
+	}
+
+	return filepath.Join(dir, name), nil
+}
+
+// isYAMLBaseline reports whether a baseline file is read and written as YAML, JSON is used otherwise.
+func isYAMLBaseline(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return true
+	default:
+		return false
+	}
+}
+
+func parseBaseline(path string, data []byte) (map[string]any, error) {
+	if !isYAMLBaseline(path) {
+		var baseline map[string]any
+
+		err := json.Unmarshal(data, &baseline)
+
+		return baseline, err
+	}
+
+	v, err := unmarshalYAML(data)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline, ok := v.(map[string]any)
+	if !ok {
+		return nil, errUnknownStructure
+	}
+
+	return baseline, nil
+}
+
+func writeBaseline(path string, cfg map[string]any) error {
+	var (
+		data []byte
+		err  error
+	)
+
+	if isYAMLBaseline(path) {
+		data, err = marshalYAML(cfg)
+	} else {
+		data, err = json.MarshalIndent(cfg, "", "  ")
+	}
+
+	if err != nil {
+		return zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	err = os.WriteFile(path, data, baselineFileMode)
+	if err != nil {
+		return zbxerr.New("failed to write baseline file").Wrap(err)
+	}
+
+	return nil
+}
+
+func marshalDrift(res configDrift) (any, error) {
+	jsonRes, err := json.Marshal(res)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
@@ -0,0 +1,296 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestReplSetConfigDriftHandler(t *testing.T) {
+	t.Parallel()
+
+	jsonData, err := os.ReadFile("testdata/replSetGetConfig.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var liveConfig map[string]any
+
+	err = json.Unmarshal(jsonData, &liveConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changedBaseline := strings.Replace(
+		strings.Replace(string(jsonData), `"version":1`, `"version":7`, 1),
+		`"host":"shard01-c:27017","priority":1`, `"host":"shard01-c:27017","priority":0`, 1,
+	)
+
+	var changed map[string]any
+
+	err = json.Unmarshal([]byte(changedBaseline), &changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changedYAML, err := marshalYAML(changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rwConcernBaseline := strings.Replace(
+		string(jsonData), `{"config":`,
+		`{"defaultRWConcern":{"defaultWriteConcern":{"w":1},"defaultReadConcern":{"level":"local"}},"config":`, 1,
+	)
+
+	liveRWConcern := bson.M{
+		"defaultWriteConcern":       bson.M{"w": "majority"},
+		"defaultReadConcern":        bson.M{"level": "local"},
+		"defaultWriteConcernSource": "global",
+		"localUpdateWallClockTime":  time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	commandNotFound := mongo.CommandError{Code: 59, Name: "CommandNotFound"}
+
+	tests := []struct {
+		name      string
+		file      string
+		baseline  *string
+		rwConcern bson.M
+		rwErr     error
+		mode      string
+		want      string
+		wantErr   bool
+	}{
+		{
+			"+noDrift",
+			"baseline.json",
+			&[]string{string(jsonData)}[0],
+			nil,
+			commandNotFound,
+			DriftModeCompare,
+			`{"baseline":"%s","snapshot":false,"drift":0,"changes":[]}`,
+			false,
+		},
+		{
+			"+versionIgnoredPriorityReported",
+			"baseline.json",
+			&changedBaseline,
+			nil,
+			commandNotFound,
+			DriftModeCompare,
+			`{"baseline":"%s","snapshot":false,"drift":1,"changes":` +
+				`[{"path":"members.2.priority","expected":0,"actual":1}]}`,
+			false,
+		},
+		{
+			"+yamlPriorityReported",
+			"baseline.yaml",
+			&[]string{string(changedYAML)}[0],
+			nil,
+			commandNotFound,
+			DriftModeCompare,
+			`{"baseline":"%s","snapshot":false,"drift":1,"changes":` +
+				`[{"path":"members.2.priority","expected":0,"actual":1}]}`,
+			false,
+		},
+		{
+			"+rwConcernReported",
+			"baseline.json",
+			&rwConcernBaseline,
+			liveRWConcern,
+			nil,
+			DriftModeCompare,
+			`{"baseline":"%s","snapshot":false,"drift":1,"changes":` +
+				`[{"path":"defaultRWConcern.defaultWriteConcern.w","expected":1,"actual":"majority"}]}`,
+			false,
+		},
+		{
+			"+snapshotOnFirstRun",
+			"baseline.json",
+			nil,
+			liveRWConcern,
+			nil,
+			DriftModeSnapshot,
+			`{"baseline":"%s","snapshot":true,"drift":0,"changes":[]}`,
+			false,
+		},
+		{
+			"+yamlSnapshotOnFirstRun",
+			"baseline.yml",
+			nil,
+			liveRWConcern,
+			nil,
+			DriftModeSnapshot,
+			`{"baseline":"%s","snapshot":true,"drift":0,"changes":[]}`,
+			false,
+		},
+		{
+			"-missingBaseline",
+			"baseline.json",
+			nil,
+			nil,
+			commandNotFound,
+			DriftModeCompare,
+			"",
+			true,
+		},
+		{
+			"-malformedBaseline",
+			"baseline.json",
+			&[]string{"{"}[0],
+			nil,
+			commandNotFound,
+			DriftModeCompare,
+			"",
+			true,
+		},
+		{
+			"-malformedYAMLBaseline",
+			"baseline.yaml",
+			&[]string{"members:\n  - &anchor 1\n"}[0],
+			nil,
+			commandNotFound,
+			DriftModeCompare,
+			"",
+			true,
+		},
+		{
+			"-rwConcernFailed",
+			"baseline.json",
+			&[]string{string(jsonData)}[0],
+			nil,
+			errors.New("not authorized on admin"),
+			DriftModeCompare,
+			"",
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dir := t.TempDir()
+			ctx := WithConfig(context.Background(), &Config{BaselinesPath: dir})
+
+			if tt.baseline != nil {
+				err := os.WriteFile(filepath.Join(dir, tt.file), []byte(*tt.baseline), 0o600)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			mockSess := &MockConn{
+				dbs: map[string]*MockMongoDatabase{
+					"admin": {
+						RunFunc: func(_, cmd string) ([]byte, error) {
+							switch cmd {
+							case "replSetGetConfig":
+								return bson.Marshal(liveConfig)
+							case "getDefaultRWConcern":
+								if tt.rwErr != nil {
+									return nil, tt.rwErr
+								}
+
+								return bson.Marshal(tt.rwConcern)
+							default:
+								return nil, errors.New("no such cmd: " + cmd)
+							}
+						},
+					},
+				},
+			}
+
+			params := map[string]string{"BaselineFile": tt.file, "Mode": tt.mode}
+
+			got, err := ReplSetConfigDriftHandler(ctx, mockSess, params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReplSetConfigDriftHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			want := strings.Replace(tt.want, "%s", tt.file, 1)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("ReplSetConfigDriftHandler() = %s", diff)
+			}
+
+			if tt.mode != DriftModeSnapshot {
+				return
+			}
+
+			// The snapshot must not report any drift on the next poll.
+			got, err = ReplSetConfigDriftHandler(ctx, mockSess, params)
+			if err != nil {
+				t.Fatalf("ReplSetConfigDriftHandler() error = %v", err)
+			}
+
+			want = strings.Replace(`{"baseline":"%s","snapshot":false,"drift":0,"changes":[]}`, "%s", tt.file, 1)
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("ReplSetConfigDriftHandler() = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_baselinePath(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		dir     string
+		file    string
+		want    string
+		wantErr error
+	}{
+		{"+file", "/etc/zabbix/baselines", "rs0.json", "/etc/zabbix/baselines/rs0.json", nil},
+		{"+subdirectory", "/etc/zabbix/baselines", "prod/rs0.json", "/etc/zabbix/baselines/prod/rs0.json", nil},
+		{"-notConfigured", "", "rs0.json", "", errBaselinesPathNotSet},
+		{"-absolute", "/etc/zabbix/baselines", "/etc/cron.d/job", "", errInvalidBaselineName},
+		{"-parent", "/etc/zabbix/baselines", "../zabbix_agent2.conf", "", errInvalidBaselineName},
+		{"-nestedParent", "/etc/zabbix/baselines", "prod/../../x.json", "", errInvalidBaselineName},
+		{"-empty", "/etc/zabbix/baselines", "", "", errInvalidBaselineName},
+		{"+yaml", "/etc/zabbix/baselines", "rs0.YML", "/etc/zabbix/baselines/rs0.YML", nil},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := baselinePath(tt.dir, tt.file)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("baselinePath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("baselinePath() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
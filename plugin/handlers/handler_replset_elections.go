@@ -0,0 +1,154 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+const stateKeyLastTerm = "rs.elections.lastTerm"
+
+type electionCandidateMetrics struct {
+	LastElectionReason string    `bson:"lastElectionReason"`
+	LastElectionDate   time.Time `bson:"lastElectionDate"`
+	ElectionTerm       int64     `bson:"electionTerm"`
+}
+
+type electionParticipantMetrics struct {
+	VotedForCandidate bool      `bson:"votedForCandidate"`
+	ElectionTerm      int64     `bson:"electionTerm"`
+	LastVoteDate      time.Time `bson:"lastVoteDate"`
+}
+
+type electionsStatus struct {
+	Term               int64                       `bson:"term"`
+	CandidateMetrics   *electionCandidateMetrics   `bson:"electionCandidateMetrics"`
+	ParticipantMetrics *electionParticipantMetrics `bson:"electionParticipantMetrics"`
+}
+
+type electionReasonCounter struct {
+	Called     int64 `bson:"called" json:"called"`
+	Successful int64 `bson:"successful" json:"successful"`
+}
+
+type electionMetrics struct {
+	StepUpCmd                      electionReasonCounter `bson:"stepUpCmd"`
+	PriorityTakeover               electionReasonCounter `bson:"priorityTakeover"`
+	CatchUpTakeover                electionReasonCounter `bson:"catchUpTakeover"`
+	ElectionTimeout                electionReasonCounter `bson:"electionTimeout"`
+	FreezeTimeout                  electionReasonCounter `bson:"freezeTimeout"`
+	NumStepDownsCausedByHigherTerm int64                 `bson:"numStepDownsCausedByHigherTerm"`
+}
+
+type electionsServerStatus struct {
+	ElectionMetrics electionMetrics `bson:"electionMetrics"`
+}
+
+type electionsResult struct {
+	Term                  int64                            `json:"term"`
+	PreviousTerm          int64                            `json:"previousTerm"`
+	Elections             int64                            `json:"elections"`
+	LastElectionReason    string                           `json:"lastElectionReason"`
+	LastElectionDate      int64                            `json:"lastElectionDate"`
+	LastElectionTerm      int64                            `json:"lastElectionTerm"`
+	LastVoteDate          int64                            `json:"lastVoteDate"`
+	VotedForCandidate     bool                             `json:"votedForCandidate"`
+	StepDownsByHigherTerm int64                            `json:"stepDownsCausedByHigherTerm"`
+	ElectionsByReason     map[string]electionReasonCounter `json:"electionsByReason"`
+}
+
+// ReplSetElectionsHandler reports elections that happened since the previous poll of the same item on the connection
+// together with the details of the last election this member took part in.
+// https://www.mongodb.com/docs/manual/reference/command/replSetGetStatus/#election-metrics
+func ReplSetElectionsHandler(ctx context.Context, s Session, params map[string]string, _ ...string) (any, error) {
+	var status electionsStatus
+
+	err := s.DB("admin").Run(ctx, &bson.D{{Key: "replSetGetStatus", Value: 1}}, &status)
+	if err != nil {
+		if strings.Contains(err.Error(), "not running with --replSet") {
+			return "{}", nil
+		}
+
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	var serverStatus electionsServerStatus
+
+	err = s.DB("admin").Run(ctx, &bson.D{{Key: "serverStatus", Value: 1}}, &serverStatus)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	res := electionsResult{
+		Term:         status.Term,
+		PreviousTerm: status.Term,
+		ElectionsByReason: map[string]electionReasonCounter{
+			"stepUpCmd":        serverStatus.ElectionMetrics.StepUpCmd,
+			"priorityTakeover": serverStatus.ElectionMetrics.PriorityTakeover,
+			"catchUpTakeover":  serverStatus.ElectionMetrics.CatchUpTakeover,
+			"electionTimeout":  serverStatus.ElectionMetrics.ElectionTimeout,
+			"freezeTimeout":    serverStatus.ElectionMetrics.FreezeTimeout,
+		},
+		StepDownsByHigherTerm: serverStatus.ElectionMetrics.NumStepDownsCausedByHigherTerm,
+	}
+
+	// The first poll of an item only remembers the term, otherwise every agent restart would look
+	// like a failover. Items with different parameters keep their own terms, so each of them sees every election.
+	if prev, ok := s.State().Swap(itemStateKey(stateKeyLastTerm, params), status.Term); ok {
+		if prevTerm, isTerm := prev.(int64); isTerm {
+			res.PreviousTerm = prevTerm
+
+			if status.Term > prevTerm {
+				res.Elections = status.Term - prevTerm
+			}
+		}
+	}
+
+	if m := status.CandidateMetrics; m != nil {
+		res.LastElectionReason = m.LastElectionReason
+		res.LastElectionTerm = m.ElectionTerm
+		res.LastElectionDate = unixOrZero(m.LastElectionDate)
+	}
+
+	if m := status.ParticipantMetrics; m != nil {
+		res.VotedForCandidate = m.VotedForCandidate
+		res.LastVoteDate = unixOrZero(m.LastVoteDate)
+
+		if m.ElectionTerm > res.LastElectionTerm {
+			res.LastElectionTerm = m.ElectionTerm
+		}
+	}
+
+	jsonRes, err := json.Marshal(res)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return t.Unix()
+}
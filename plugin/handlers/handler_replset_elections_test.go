@@ -0,0 +1,189 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestReplSetElectionsHandler(t *testing.T) {
+	t.Parallel()
+
+	electionDate := time.Unix(1700000000, 0)
+
+	statusResp := func(term int64) bson.M {
+		return bson.M{
+			"set":  "rs0",
+			"term": term,
+			"electionCandidateMetrics": bson.M{
+				"lastElectionReason": "electionTimeout",
+				"lastElectionDate":   electionDate,
+				"electionTerm":       term,
+			},
+			"ok": 1,
+		}
+	}
+
+	serverStatusResp := bson.M{
+		"electionMetrics": bson.M{
+			"electionTimeout":                bson.M{"called": 2, "successful": 1},
+			"numStepDownsCausedByHigherTerm": 3,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		terms   []int64
+		runErr  error
+		want    []string
+		wantErr bool
+	}{
+		{
+			"+firstPollRemembersTerm",
+			[]int64{5},
+			nil,
+			[]string{
+				`{"term":5,"previousTerm":5,"elections":0,"lastElectionReason":"electionTimeout",` +
+					`"lastElectionDate":1700000000,"lastElectionTerm":5,"lastVoteDate":0,"votedForCandidate":false,` +
+					`"stepDownsCausedByHigherTerm":3,"electionsByReason":{"catchUpTakeover":{"called":0,"successful":0},` +
+					`"electionTimeout":{"called":2,"successful":1},"freezeTimeout":{"called":0,"successful":0},` +
+					`"priorityTakeover":{"called":0,"successful":0},"stepUpCmd":{"called":0,"successful":0}}}`,
+			},
+			false,
+		},
+		{
+			"+failoverBetweenPolls",
+			[]int64{5, 7},
+			nil,
+			[]string{
+				"",
+				`{"term":7,"previousTerm":5,"elections":2,"lastElectionReason":"electionTimeout",` +
+					`"lastElectionDate":1700000000,"lastElectionTerm":7,"lastVoteDate":0,"votedForCandidate":false,` +
+					`"stepDownsCausedByHigherTerm":3,"electionsByReason":{"catchUpTakeover":{"called":0,"successful":0},` +
+					`"electionTimeout":{"called":2,"successful":1},"freezeTimeout":{"called":0,"successful":0},` +
+					`"priorityTakeover":{"called":0,"successful":0},"stepUpCmd":{"called":0,"successful":0}}}`,
+			},
+			false,
+		},
+		{
+			"+notReplicaSet",
+			[]int64{0},
+			errors.New("not running with --replSet"),
+			[]string{"{}"},
+			false,
+		},
+		{
+			"-commandErr",
+			[]int64{0},
+			errors.New("fail"),
+			[]string{""},
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var term int64
+
+			mockSess := &MockConn{
+				dbs: map[string]*MockMongoDatabase{
+					"admin": {
+						RunFunc: func(_, cmd string) ([]byte, error) {
+							if tt.runErr != nil {
+								return nil, tt.runErr
+							}
+
+							if cmd == "serverStatus" {
+								return bson.Marshal(serverStatusResp)
+							}
+
+							return bson.Marshal(statusResp(term))
+						},
+					},
+				},
+			}
+
+			for i := range tt.terms {
+				term = tt.terms[i]
+
+				got, err := ReplSetElectionsHandler(context.Background(), mockSess, nil)
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("ReplSetElectionsHandler() error = %v, wantErr %v", err, tt.wantErr)
+				}
+
+				if tt.want[i] == "" {
+					continue
+				}
+
+				if diff := cmp.Diff(tt.want[i], got); diff != "" {
+					t.Fatalf("ReplSetElectionsHandler() = %s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestReplSetElectionsHandler_items(t *testing.T) {
+	t.Parallel()
+
+	var term int64
+
+	mockSess := &MockConn{
+		dbs: map[string]*MockMongoDatabase{
+			"admin": {
+				RunFunc: func(_, cmd string) ([]byte, error) {
+					if cmd == "serverStatus" {
+						return bson.Marshal(bson.M{})
+					}
+
+					return bson.Marshal(bson.M{"set": "rs0", "term": term, "ok": 1})
+				},
+			},
+		},
+	}
+
+	// Items polling the same connection with different parameters must all see the election.
+	items := []map[string]string{{}, {"Fields": "elections"}, {"Format": "flat"}}
+
+	for _, term = range []int64{5, 7} {
+		for _, params := range items {
+			got, err := ReplSetElectionsHandler(context.Background(), mockSess, params)
+			if err != nil {
+				t.Fatalf("ReplSetElectionsHandler() error = %v", err)
+			}
+
+			var res electionsResult
+
+			err = json.Unmarshal([]byte(got.(string)), &res)
+			if err != nil {
+				t.Fatalf("ReplSetElectionsHandler() returned invalid JSON: %v", err)
+			}
+
+			want := term - 5
+			if res.Elections != want {
+				t.Fatalf("ReplSetElectionsHandler(%v) elections = %d, want %d", params, res.Elections, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,139 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+const (
+	syncPhaseStarting   = "starting"
+	syncPhaseCloning    = "cloning"
+	syncPhaseApplyingOp = "applying oplog"
+)
+
+type initialSyncProgress struct {
+	Phase                    string `json:"phase"`
+	DatabasesCloned          int64  `json:"databasesCloned"`
+	DatabasesTotal           int64  `json:"databasesTotal"`
+	BytesCopied              int64  `json:"bytesCopied"`
+	ApproxTotalBytes         int64  `json:"approxTotalBytes"`
+	RemainingEstimatedMillis int64  `json:"remainingEstimatedMillis"`
+	ElapsedMillis            int64  `json:"elapsedMillis"`
+	FailedAttempts           int64  `json:"failedAttempts"`
+	MaxFailedAttempts        int64  `json:"maxFailedAttempts"`
+	LastFailure              string `json:"lastFailure"`
+}
+
+// ReplSetInitialSyncHandler returns the progress of an initial sync running on the member.
+// https://www.mongodb.com/docs/manual/reference/command/replSetGetStatus/#initial-sync-status
+func ReplSetInitialSyncHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
+	var replSetGetStatus bson.M
+
+	err := s.DB("admin").Run(
+		ctx,
+		&bson.D{
+			{Key: "replSetGetStatus", Value: 1},
+			{Key: "initialSync", Value: 1},
+		},
+		&replSetGetStatus,
+	)
+	if err != nil {
+		if strings.Contains(err.Error(), "not running with --replSet") {
+			return "{}", nil
+		}
+
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	status, ok := replSetGetStatus["initialSyncStatus"].(bson.M)
+	if !ok {
+		return "{}", nil
+	}
+
+	jsonRes, err := json.Marshal(parseInitialSyncStatus(status))
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+func parseInitialSyncStatus(status bson.M) initialSyncProgress {
+	res := initialSyncProgress{
+		Phase:                    syncPhaseStarting,
+		BytesCopied:              asInt64(status["approxTotalBytesCopied"]),
+		ApproxTotalBytes:         asInt64(status["approxTotalDataSize"]),
+		RemainingEstimatedMillis: asInt64(status["remainingInitialSyncEstimatedMillis"]),
+		ElapsedMillis:            asInt64(status["totalInitialSyncElapsedMillis"]),
+		FailedAttempts:           asInt64(status["failedInitialSyncAttempts"]),
+		MaxFailedAttempts:        asInt64(status["maxFailedInitialSyncAttempts"]),
+	}
+
+	if attempts, ok := status["initialSyncAttempts"].(bson.A); ok && len(attempts) > 0 {
+		if last, isDoc := attempts[len(attempts)-1].(bson.M); isDoc {
+			res.LastFailure, _ = last["status"].(string)
+		}
+	}
+
+	dbs, ok := status["databases"].(bson.M)
+	if !ok {
+		return res
+	}
+
+	res.DatabasesCloned = asInt64(dbs["databasesCloned"])
+
+	// databasesToClone is only reported by 4.4+, older servers list a sub-document per database instead.
+	if total, found := dbs["databasesToClone"]; found {
+		res.DatabasesTotal = asInt64(total)
+	} else {
+		for _, v := range dbs {
+			if _, isDoc := v.(bson.M); isDoc {
+				res.DatabasesTotal++
+			}
+		}
+	}
+
+	switch {
+	case res.DatabasesTotal == 0:
+	case res.DatabasesCloned < res.DatabasesTotal:
+		res.Phase = syncPhaseCloning
+	default:
+		res.Phase = syncPhaseApplyingOp
+	}
+
+	return res
+}
+
+// asInt64 converts any BSON numeric value to int64, returning zero for other types.
+func asInt64(v any) int64 {
+	switch n := v.(type) {
+	case int32:
+		return int64(n)
+	case int64:
+		return n
+	case float64:
+		return int64(n)
+	case int:
+		return int64(n)
+	default:
+		return 0
+	}
+}
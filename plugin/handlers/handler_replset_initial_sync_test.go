@@ -0,0 +1,131 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestReplSetInitialSyncHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		resp    bson.M
+		err     error
+		want    any
+		wantErr bool
+	}{
+		{
+			"+cloning",
+			bson.M{
+				"initialSyncStatus": bson.M{
+					"failedInitialSyncAttempts":           int32(1),
+					"maxFailedInitialSyncAttempts":        int32(10),
+					"totalInitialSyncElapsedMillis":       int64(60000),
+					"approxTotalDataSize":                 int64(4000),
+					"approxTotalBytesCopied":              int64(1000),
+					"remainingInitialSyncEstimatedMillis": int64(180000),
+					"initialSyncAttempts": bson.A{
+						bson.M{"durationMillis": 10, "status": "InitialSyncFailure: sync source went away"},
+					},
+					"databases": bson.M{
+						"databasesToClone": int32(3),
+						"databasesCloned":  int32(1),
+						"admin":            bson.M{"collections": 1},
+					},
+				},
+				"ok": 1,
+			},
+			nil,
+			`{"phase":"cloning","databasesCloned":1,"databasesTotal":3,"bytesCopied":1000,` +
+				`"approxTotalBytes":4000,"remainingEstimatedMillis":180000,"elapsedMillis":60000,` +
+				`"failedAttempts":1,"maxFailedAttempts":10,"lastFailure":"InitialSyncFailure: sync source went away"}`,
+			false,
+		},
+		{
+			"+legacyDatabasesCount",
+			bson.M{
+				"initialSyncStatus": bson.M{
+					"databases": bson.M{
+						"databasesCloned": int32(2),
+						"admin":           bson.M{"collections": 1},
+						"app":             bson.M{"collections": 4},
+					},
+				},
+			},
+			nil,
+			`{"phase":"applying oplog","databasesCloned":2,"databasesTotal":2,"bytesCopied":0,` +
+				`"approxTotalBytes":0,"remainingEstimatedMillis":0,"elapsedMillis":0,` +
+				`"failedAttempts":0,"maxFailedAttempts":0,"lastFailure":""}`,
+			false,
+		},
+		{
+			"+noSyncRunning",
+			bson.M{"set": "rs0", "ok": 1},
+			nil,
+			"{}",
+			false,
+		},
+		{
+			"+notReplicaSet",
+			nil,
+			errors.New("not running with --replSet"),
+			"{}",
+			false,
+		},
+		{
+			"-commandErr",
+			nil,
+			errors.New("fail"),
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockSess := &MockConn{
+				dbs: map[string]*MockMongoDatabase{
+					"admin": {
+						RunFunc: func(_, _ string) ([]byte, error) {
+							if tt.err != nil {
+								return nil, tt.err
+							}
+
+							return bson.Marshal(tt.resp)
+						},
+					},
+				},
+			}
+
+			got, err := ReplSetInitialSyncHandler(context.Background(), mockSess, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReplSetInitialSyncHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("ReplSetInitialSyncHandler() = %s", diff)
+			}
+		})
+	}
+}
@@ -16,7 +16,6 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"strings"
 	"time"
@@ -146,7 +145,7 @@ func injectExtendedMembersStats(raw []any) error {
 
 // ReplSetStatusHandler
 // https://docs.mongodb.com/manual/reference/command/replSetGetStatus/index.html
-func ReplSetStatusHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
+func ReplSetStatusHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
 	var replSetGetStatus map[string]any
 
 	err := s.DB("admin").Run(
@@ -181,7 +180,7 @@ func ReplSetStatusHandler(ctx context.Context, s Session, _ map[string]string) (
 		}
 	}
 
-	jsonRes, err := json.Marshal(replSetGetStatus)
+	jsonRes, err := marshalDocument(ctx, replSetGetStatus)
 	if err != nil {
 		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
 	}
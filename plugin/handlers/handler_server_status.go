@@ -16,7 +16,6 @@ package handlers
 
 import (
 	"context"
-	"encoding/json"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"golang.zabbix.com/sdk/zbxerr"
@@ -24,27 +23,22 @@ import (
 
 // ServerStatusHandler
 // https://docs.mongodb.com/manual/reference/command/serverStatus/#dbcmd.serverStatus
-func ServerStatusHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
+// Sections not selected by the Fields parameter are excluded from the reply by the server.
+func ServerStatusHandler(ctx context.Context, s Session, params map[string]string, _ ...string) (any, error) {
+	fields, err := ParseFields(params["Fields"])
+	if err != nil {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(err)
+	}
+
+	cmd := serverStatusCommand(fields)
 	serverStatus := &bson.M{}
-	err := s.DB("admin").Run(ctx,
-		&bson.D{
-			{
-				Key:   "serverStatus",
-				Value: 1,
-			},
-			{
-				Key:   "recordStats",
-				Value: 0,
-			},
-		},
-		serverStatus,
-	)
 
+	err = s.DB("admin").Run(ctx, &cmd, serverStatus)
 	if err != nil {
 		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
 	}
 
-	jsonRes, err := json.Marshal(serverStatus)
+	jsonRes, err := marshalDocument(ctx, serverStatus)
 	if err != nil {
 		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
 	}
@@ -41,7 +41,7 @@ type shEntry struct {
 
 // ShardsDiscoveryHandler
 // https://docs.mongodb.com/manual/reference/method/sh.status/#sh.status
-func ShardsDiscoveryHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
+func ShardsDiscoveryHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
 	var shards []shEntry
 
 	opts := options.Find()
@@ -0,0 +1,202 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+const hoursInDay = 24
+
+var errNoPeerCertificates = errors.New("server did not present a certificate")
+
+// TLSCertOptions describes the endpoint to check and the TLS config of the session.
+type TLSCertOptions struct {
+	Addr   string
+	Config *tls.Config
+}
+
+type certInfo struct {
+	Subject       string   `json:"subject"`
+	Issuer        string   `json:"issuer"`
+	SANs          []string `json:"sans"`
+	SerialNumber  string   `json:"serialNumber"`
+	NotBefore     int64    `json:"notBefore"`
+	NotAfter      int64    `json:"notAfter"`
+	DaysRemaining int      `json:"daysRemaining"`
+}
+
+type clientCertInfo struct {
+	certInfo
+	Verified    bool   `json:"verified"`
+	VerifyError string `json:"verifyError"`
+}
+
+type tlsCertResult struct {
+	Verified      bool            `json:"verified"`
+	VerifyError   string          `json:"verifyError"`
+	DaysRemaining int             `json:"daysRemaining"`
+	Chain         []certInfo      `json:"chain"`
+	Client        *clientCertInfo `json:"client"`
+}
+
+// TLSCertHandler performs a TLS handshake with the server and returns its certificate chain, the number of days
+// until the server certificate expires and whether it verifies against the root CAs and the revocation check of the
+// config. The client certificate of the session, if any, gets the same checks.
+// The handshake itself accepts any certificate, so that expired or untrusted ones can still be reported.
+func TLSCertHandler(ctx context.Context, opts TLSCertOptions) (any, error) {
+	cfg := opts.Config.Clone()
+	cfg.InsecureSkipVerify = true //nolint:gosec // the certificate is verified below to report the result
+	cfg.VerifyPeerCertificate = nil
+
+	dialer := tls.Dialer{Config: cfg}
+
+	conn, err := dialer.DialContext(ctx, "tcp", opts.Addr)
+	if err != nil {
+		return nil, zbxerr.ErrorConnectionFailed.Wrap(err)
+	}
+
+	defer conn.Close() //nolint:errcheck
+
+	chain := conn.(*tls.Conn).ConnectionState().PeerCertificates //nolint:forcetypeassert
+	if len(chain) == 0 {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(errNoPeerCertificates)
+	}
+
+	now := time.Now()
+
+	res := tlsCertResult{
+		DaysRemaining: daysRemaining(chain[0], now),
+		Chain:         make([]certInfo, 0, len(chain)),
+	}
+
+	for _, c := range chain {
+		res.Chain = append(res.Chain, newCertInfo(c, now))
+	}
+
+	res.Verified, res.VerifyError = verifyServerCert(opts.Config, chain)
+
+	if len(opts.Config.Certificates) > 0 {
+		res.Client, err = newClientCertInfo(opts.Config.Certificates[0], opts.Config.RootCAs, now)
+		if err != nil {
+			return nil, zbxerr.ErrorInvalidConfiguration.Wrap(err)
+		}
+	}
+
+	jsonRes, err := json.Marshal(res)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+// verifyServerCert verifies the server chain against the config roots and server name, then runs the additional
+// checks of the config, such as the revocation list.
+func verifyServerCert(cfg *tls.Config, chain []*x509.Certificate) (bool, string) {
+	ok, verifyErr := verifyCert(chain, cfg.RootCAs, cfg.ServerName, x509.ExtKeyUsageServerAuth)
+	if !ok || cfg.VerifyPeerCertificate == nil {
+		return ok, verifyErr
+	}
+
+	rawCerts := make([][]byte, 0, len(chain))
+	for _, c := range chain {
+		rawCerts = append(rawCerts, c.Raw)
+	}
+
+	err := cfg.VerifyPeerCertificate(rawCerts, nil)
+	if err != nil {
+		return false, err.Error()
+	}
+
+	return true, ""
+}
+
+func newClientCertInfo(cert tls.Certificate, roots *x509.CertPool, now time.Time) (*clientCertInfo, error) {
+	chain := make([]*x509.Certificate, 0, len(cert.Certificate))
+
+	for _, der := range cert.Certificate {
+		c, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, err
+		}
+
+		chain = append(chain, c)
+	}
+
+	info := &clientCertInfo{certInfo: newCertInfo(chain[0], now)}
+	info.Verified, info.VerifyError = verifyCert(chain, roots, "", x509.ExtKeyUsageClientAuth)
+
+	return info, nil
+}
+
+func verifyCert(
+	chain []*x509.Certificate, roots *x509.CertPool, dnsName string, usage x509.ExtKeyUsage,
+) (bool, string) {
+	intermediates := x509.NewCertPool()
+	for _, c := range chain[1:] {
+		intermediates.AddCert(c)
+	}
+
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       dnsName,
+		KeyUsages:     []x509.ExtKeyUsage{usage},
+	})
+	if err != nil {
+		return false, err.Error()
+	}
+
+	return true, ""
+}
+
+func newCertInfo(c *x509.Certificate, now time.Time) certInfo {
+	sans := make([]string, 0, len(c.DNSNames)+len(c.IPAddresses))
+	sans = append(sans, c.DNSNames...)
+
+	for _, ip := range c.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+
+	return certInfo{
+		Subject:       c.Subject.String(),
+		Issuer:        c.Issuer.String(),
+		SANs:          sans,
+		SerialNumber:  c.SerialNumber.String(),
+		NotBefore:     c.NotBefore.Unix(),
+		NotAfter:      c.NotAfter.Unix(),
+		DaysRemaining: daysRemaining(c, now),
+	}
+}
+
+// daysRemaining returns the number of whole days until the certificate expires, negative if it already has.
+func daysRemaining(c *x509.Certificate, now time.Time) int {
+	left := c.NotAfter.Sub(now)
+	days := int(left.Hours() / hoursInDay)
+
+	if left < 0 && left.Hours() != float64(days*hoursInDay) {
+		days--
+	}
+
+	return days
+}
@@ -0,0 +1,179 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"golang.zabbix.com/plugin/mongodb/plugin/internal/tlstest"
+)
+
+func TestTLSCertHandler(t *testing.T) {
+	t.Parallel()
+
+	ca := tlstest.NewCA(t, "Test CA")
+	otherCA := tlstest.NewCA(t, "Other CA")
+	server := ca.Issue(t, 10, "mongo", time.Now().Add(30*24*time.Hour+time.Hour), x509.ExtKeyUsageServerAuth)
+	expired := ca.Issue(t, 11, "expired", time.Now().Add(-49*time.Hour), x509.ExtKeyUsageServerAuth)
+	clientCert := ca.Issue(t, 20, "agent", time.Now().Add(10*24*time.Hour+time.Hour), x509.ExtKeyUsageClientAuth)
+	errRevoked := errors.New("server certificate is revoked")
+
+	serverConfig := func(cert tls.Certificate) *tls.Config {
+		return &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			ClientAuth:   tls.RequestClientCert,
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+
+	addr := tlstest.StartServer(t, serverConfig(server))
+	expiredAddr := tlstest.StartServer(t, serverConfig(expired))
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	closedAddr := closed.Addr().String()
+	closed.Close()
+
+	type want struct {
+		verified       bool
+		daysRemaining  int
+		subject        string
+		clientVerified bool
+		clientDays     int
+	}
+
+	tests := []struct {
+		name    string
+		opts    TLSCertOptions
+		want    want
+		wantErr bool
+	}{
+		{
+			"+verified",
+			TLSCertOptions{Addr: addr, Config: &tls.Config{ServerName: "localhost", RootCAs: ca.Pool()}},
+			want{verified: true, daysRemaining: 30, subject: "CN=mongo"},
+			false,
+		},
+		{
+			"+wrongServerName",
+			TLSCertOptions{Addr: addr, Config: &tls.Config{ServerName: "other.example.com", RootCAs: ca.Pool()}},
+			want{verified: false, daysRemaining: 30, subject: "CN=mongo"},
+			false,
+		},
+		{
+			"+untrustedCA",
+			TLSCertOptions{Addr: addr, Config: &tls.Config{ServerName: "localhost", RootCAs: otherCA.Pool()}},
+			want{verified: false, daysRemaining: 30, subject: "CN=mongo"},
+			false,
+		},
+		{
+			"+expired",
+			TLSCertOptions{Addr: expiredAddr, Config: &tls.Config{ServerName: "localhost", RootCAs: ca.Pool()}},
+			want{verified: false, daysRemaining: -3, subject: "CN=expired"},
+			false,
+		},
+		{
+			"+clientCert",
+			TLSCertOptions{Addr: addr, Config: &tls.Config{
+				ServerName: "localhost", RootCAs: ca.Pool(), Certificates: []tls.Certificate{clientCert},
+			}},
+			want{verified: true, daysRemaining: 30, subject: "CN=mongo", clientVerified: true, clientDays: 10},
+			false,
+		},
+		{
+			"+revoked",
+			TLSCertOptions{Addr: addr, Config: &tls.Config{
+				ServerName: "localhost",
+				RootCAs:    ca.Pool(),
+				VerifyPeerCertificate: func([][]byte, [][]*x509.Certificate) error {
+					return errRevoked
+				},
+			}},
+			want{verified: false, daysRemaining: 30, subject: "CN=mongo"},
+			false,
+		},
+		{
+			"-connectionRefused",
+			TLSCertOptions{Addr: closedAddr, Config: &tls.Config{ServerName: "localhost", RootCAs: ca.Pool()}},
+			want{},
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			got, err := TLSCertHandler(ctx, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("TLSCertHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			var res tlsCertResult
+
+			err = json.Unmarshal([]byte(got.(string)), &res)
+			if err != nil {
+				t.Fatalf("failed to unmarshal result: %v", err)
+			}
+
+			if res.Verified != tt.want.verified || (res.VerifyError == "") != tt.want.verified {
+				t.Errorf("verified = %v (%q), want %v", res.Verified, res.VerifyError, tt.want.verified)
+			}
+
+			if res.DaysRemaining != tt.want.daysRemaining {
+				t.Errorf("daysRemaining = %d, want %d", res.DaysRemaining, tt.want.daysRemaining)
+			}
+
+			if len(res.Chain) != 1 || res.Chain[0].Subject != tt.want.subject || res.Chain[0].Issuer != "CN=Test CA" {
+				t.Fatalf("chain = %+v, want %s issued by CN=Test CA", res.Chain, tt.want.subject)
+			}
+
+			if len(res.Chain[0].SANs) != 3 {
+				t.Errorf("sans = %v, want localhost, %s and 127.0.0.1", res.Chain[0].SANs, tlstest.ServerName)
+			}
+
+			if len(tt.opts.Config.Certificates) == 0 {
+				if res.Client != nil {
+					t.Errorf("client = %+v, want nil", res.Client)
+				}
+
+				return
+			}
+
+			if res.Client == nil || res.Client.Verified != tt.want.clientVerified ||
+				res.Client.DaysRemaining != tt.want.clientDays {
+				t.Errorf("client = %+v, want verified %v, %d days", res.Client, tt.want.clientVerified, tt.want.clientDays)
+			}
+		})
+	}
+}
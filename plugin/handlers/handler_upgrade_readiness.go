@@ -0,0 +1,284 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+const (
+	readinessPass = "pass"
+	readinessWarn = "warn"
+	readinessFail = "fail"
+)
+
+// memberStateArbiter is the replSetGetStatus state of arbiters.
+const memberStateArbiter = 7
+
+type deprecatedOption struct {
+	path string
+	note string
+}
+
+// deprecatedOptions are configuration file settings deprecated or removed in recent releases.
+var deprecatedOptions = []deprecatedOption{
+	{"master", "master-slave replication is removed in 4.0"},
+	{"slave", "master-slave replication is removed in 4.0"},
+	{"net.http", "the HTTP interface is removed in 3.6"},
+	{"net.ssl", "net.ssl settings are deprecated since 4.2, use net.tls"},
+	{"net.serviceExecutor", "net.serviceExecutor is removed in 5.0"},
+	{"storage.mmapv1", "the MMAPv1 storage engine is removed in 4.2"},
+	{"storage.indexBuildRetry", "storage.indexBuildRetry is removed in 4.4"},
+	{"storage.journal.enabled", "storage.journal.enabled is removed in 7.0"},
+	{"replication.secondaryIndexPrefetch", "replication.secondaryIndexPrefetch is removed in 4.2"},
+	{"replication.enableMajorityReadConcern", "replication.enableMajorityReadConcern is deprecated since 5.0"},
+	{"setParameter.failIndexKeyTooLong", "failIndexKeyTooLong is removed in 4.4"},
+}
+
+type memberVersion struct {
+	Host    string `json:"host"`
+	Version string `json:"version"`
+}
+
+type upgradeReadiness struct {
+	Status             string          `json:"status"`
+	Reasons            []string        `json:"reasons"`
+	BinaryVersion      string          `json:"binaryVersion"`
+	FCV                string          `json:"featureCompatibilityVersion"`
+	FCVLagsBinary      bool            `json:"fcvLagsBinary"`
+	MembersSameVersion bool            `json:"membersSameVersion"`
+	Members            []memberVersion `json:"members"`
+	DeprecatedSettings []string        `json:"deprecatedSettings"`
+}
+
+type rsMembers struct {
+	Members []struct {
+		Name  string `bson:"name"`
+		Self  bool   `bson:"self"`
+		State int    `bson:"state"`
+	} `bson:"members"`
+}
+
+// UpgradeReadinessHandler checks if the deployment is ready for the next major version upgrade:
+// the feature compatibility version must match the binary, all replica set members must run the same version
+// and no removed settings may be used. Arbiters are not checked, since they have no users to authenticate
+// the session credentials with. Every other member gets an equal share of the time left, so that an unreachable
+// member is reported without failing the whole check.
+func UpgradeReadinessHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
+	version, err := getVersion(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	binary, err := parseVersion(version)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotParseResult.Wrap(err)
+	}
+
+	res := &upgradeReadiness{
+		Status:             readinessPass,
+		Reasons:            []string{},
+		BinaryVersion:      version,
+		MembersSameVersion: true,
+		Members:            []memberVersion{},
+		DeprecatedSettings: []string{},
+	}
+
+	res.checkFCV(ctx, s, binary)
+
+	err = res.checkMembers(ctx, s, version)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	err = res.checkSettings(ctx, s)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	jsonRes, err := json.Marshal(res)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+func (r *upgradeReadiness) checkFCV(ctx context.Context, s Session, binary serverVersion) {
+	params, err := getParameters(ctx, s, []string{"featureCompatibilityVersion"})
+	if err != nil {
+		r.report(readinessWarn, "cannot get feature compatibility version: %s", err.Error())
+
+		return
+	}
+
+	if fcv, ok := params["featureCompatibilityVersion"].(bson.M); ok {
+		r.FCV, _ = fcv["version"].(string)
+	}
+
+	if r.FCV == "" {
+		r.report(readinessWarn, "feature compatibility version is not reported")
+
+		return
+	}
+
+	if r.FCV != binary.release() {
+		r.FCVLagsBinary = true
+		r.report(readinessFail, "feature compatibility version %s lags binary version %s", r.FCV, binary.release())
+	}
+}
+
+func (r *upgradeReadiness) checkMembers(ctx context.Context, s Session, selfVersion string) error {
+	role, err := ServerRole(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	// Only replica set members know the other members of the deployment.
+	if role == RoleMongos || role == RoleStandalone {
+		return nil
+	}
+
+	var status rsMembers
+
+	err = s.DB("admin").Run(ctx, &bson.D{{Key: "replSetGetStatus", Value: 1}}, &status)
+	if err != nil {
+		if isNotReplSetMember(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	peers := 0
+
+	for _, m := range status.Members {
+		if !m.Self && m.State != memberStateArbiter {
+			peers++
+		}
+	}
+
+	for _, m := range status.Members {
+		if m.State == memberStateArbiter {
+			continue
+		}
+
+		member := memberVersion{Host: m.Name, Version: selfVersion}
+
+		if !m.Self {
+			member.Version, err = getPeerVersion(ctx, s, m.Name, peers)
+			if err != nil {
+				r.report(readinessWarn, "cannot get version of member %s: %s", m.Name, err.Error())
+			}
+
+			peers--
+		}
+
+		r.Members = append(r.Members, member)
+
+		if member.Version != "" && member.Version != selfVersion {
+			r.MembersSameVersion = false
+		}
+	}
+
+	if !r.MembersSameVersion {
+		r.report(readinessFail, "replica set members run different versions")
+	}
+
+	return nil
+}
+
+func (r *upgradeReadiness) checkSettings(ctx context.Context, s Session) error {
+	var opts bson.M
+
+	err := s.DB("admin").Run(ctx, &bson.D{{Key: "getCmdLineOpts", Value: 1}}, &opts)
+	if err != nil {
+		return err
+	}
+
+	parsed, _ := opts["parsed"].(bson.M)
+
+	for _, o := range deprecatedOptions {
+		if lookupPath(parsed, o.path) {
+			r.DeprecatedSettings = append(r.DeprecatedSettings, o.path)
+			r.report(readinessWarn, "%s", o.note)
+		}
+	}
+
+	return nil
+}
+
+// report adds a reason and raises the status to the given level.
+func (r *upgradeReadiness) report(status, format string, args ...any) {
+	r.Reasons = append(r.Reasons, fmt.Sprintf(format, args...))
+
+	if status == readinessFail || r.Status == readinessPass {
+		r.Status = status
+	}
+}
+
+// getPeerVersion returns the version of another member within its share of the time left to the remaining members.
+func getPeerVersion(ctx context.Context, s Session, host string, remaining int) (string, error) {
+	peerCtx, cancel := ShareContext(ctx, remaining)
+	defer cancel()
+
+	peer, release, err := s.Peer(peerCtx, host)
+	if err != nil {
+		return "", err
+	}
+
+	defer release()
+
+	return getVersion(peerCtx, peer)
+}
+
+// isNotReplSetMember reports whether replSetGetStatus failed because the server is not a replica set member:
+// standalone servers are not running with --replSet and mongos does not support the command.
+func isNotReplSetMember(err error) bool {
+	var cmdErr mongo.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.Name == "CommandNotFound" {
+		return true
+	}
+
+	return strings.Contains(err.Error(), "not running with --replSet") ||
+		strings.Contains(err.Error(), "not supported through mongos")
+}
+
+// lookupPath reports whether a dotted path exists in the document.
+func lookupPath(doc bson.M, path string) bool {
+	var cur any = doc
+
+	for _, key := range strings.Split(path, ".") {
+		m, ok := cur.(bson.M)
+		if !ok {
+			return false
+		}
+
+		cur, ok = m[key]
+		if !ok {
+			return false
+		}
+	}
+
+	return true
+}
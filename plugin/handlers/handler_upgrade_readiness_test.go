@@ -0,0 +1,257 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func newReadinessMock(version, fcv string, parsed bson.M, members bson.A) *MockConn {
+	return &MockConn{
+		dbs: map[string]*MockMongoDatabase{
+			"admin": {
+				RunFunc: func(_, cmd string) ([]byte, error) {
+					switch cmd {
+					case "buildInfo":
+						return bson.Marshal(bson.M{"version": version})
+					case "getParameter":
+						return bson.Marshal(bson.M{"featureCompatibilityVersion": bson.M{"version": fcv}, "ok": 1})
+					case "getCmdLineOpts":
+						return bson.Marshal(bson.M{"parsed": parsed})
+					case "isMaster":
+						if members == nil {
+							return bson.Marshal(bson.M{"ismaster": true})
+						}
+
+						return bson.Marshal(bson.M{"ismaster": true, "setName": "rs0"})
+					case "replSetGetStatus":
+						if members == nil {
+							return nil, errors.New("not running with --replSet")
+						}
+
+						return bson.Marshal(bson.M{"members": members})
+					}
+
+					return nil, errors.New("no such cmd: " + cmd)
+				},
+			},
+		},
+	}
+}
+
+func TestUpgradeReadinessHandler(t *testing.T) {
+	t.Parallel()
+
+	members := bson.A{
+		bson.M{"name": "mongo1:27017", "self": true},
+		bson.M{"name": "mongo2:27017"},
+	}
+
+	mixed := newReadinessMock("6.0.14", "6.0", bson.M{}, members)
+	mixed.peers = map[string]*MockConn{"mongo2:27017": newReadinessMock("5.0.26", "5.0", bson.M{}, nil)}
+
+	same := newReadinessMock("6.0.14", "6.0", bson.M{}, members)
+	same.peers = map[string]*MockConn{"mongo2:27017": newReadinessMock("6.0.14", "6.0", bson.M{}, nil)}
+
+	unreachable := newReadinessMock("6.0.14", "6.0", bson.M{}, members)
+
+	// mongos does not support replSetGetStatus, which must not be run at all.
+	mongos := newReadinessMock("6.0.14", "6.0", bson.M{}, members)
+	runMongos := mongos.dbs["admin"].RunFunc
+	mongos.dbs["admin"].RunFunc = func(db, cmd string) ([]byte, error) {
+		switch cmd {
+		case "isMaster":
+			return bson.Marshal(bson.M{"ismaster": true, "msg": "isdbgrid"})
+		case "replSetGetStatus":
+			return nil, mongo.CommandError{Code: 59, Name: "CommandNotFound"}
+		}
+
+		return runMongos(db, cmd)
+	}
+
+	tests := []struct {
+		name    string
+		conn    *MockConn
+		want    string
+		wantErr bool
+	}{
+		{
+			"+standalonePass",
+			newReadinessMock("6.0.14", "6.0", bson.M{"net": bson.M{"port": 27017}}, nil),
+			`{"status":"pass","reasons":[],"binaryVersion":"6.0.14","featureCompatibilityVersion":"6.0",` +
+				`"fcvLagsBinary":false,"membersSameVersion":true,"members":[],"deprecatedSettings":[]}`,
+			false,
+		},
+		{
+			"+fcvLags",
+			newReadinessMock("6.0.14-ent", "5.0", bson.M{}, nil),
+			`{"status":"fail","reasons":["feature compatibility version 5.0 lags binary version 6.0"],` +
+				`"binaryVersion":"6.0.14-ent","featureCompatibilityVersion":"5.0","fcvLagsBinary":true,` +
+				`"membersSameVersion":true,"members":[],"deprecatedSettings":[]}`,
+			false,
+		},
+		{
+			"+deprecatedSettings",
+			newReadinessMock("6.0.14", "6.0", bson.M{
+				"net":     bson.M{"ssl": bson.M{"mode": "requireSSL"}},
+				"storage": bson.M{"journal": bson.M{"enabled": true}},
+			}, nil),
+			`{"status":"warn","reasons":["net.ssl settings are deprecated since 4.2, use net.tls",` +
+				`"storage.journal.enabled is removed in 7.0"],"binaryVersion":"6.0.14",` +
+				`"featureCompatibilityVersion":"6.0","fcvLagsBinary":false,"membersSameVersion":true,` +
+				`"members":[],"deprecatedSettings":["net.ssl","storage.journal.enabled"]}`,
+			false,
+		},
+		{
+			"+sameMembers",
+			same,
+			`{"status":"pass","reasons":[],"binaryVersion":"6.0.14","featureCompatibilityVersion":"6.0",` +
+				`"fcvLagsBinary":false,"membersSameVersion":true,"members":[{"host":"mongo1:27017",` +
+				`"version":"6.0.14"},{"host":"mongo2:27017","version":"6.0.14"}],"deprecatedSettings":[]}`,
+			false,
+		},
+		{
+			"+mixedMembers",
+			mixed,
+			`{"status":"fail","reasons":["replica set members run different versions"],` +
+				`"binaryVersion":"6.0.14","featureCompatibilityVersion":"6.0","fcvLagsBinary":false,` +
+				`"membersSameVersion":false,"members":[{"host":"mongo1:27017","version":"6.0.14"},` +
+				`{"host":"mongo2:27017","version":"5.0.26"}],"deprecatedSettings":[]}`,
+			false,
+		},
+		{
+			"+unreachableMember",
+			unreachable,
+			`{"status":"warn","reasons":["cannot get version of member mongo2:27017: ` +
+				`Connection failed."],"binaryVersion":"6.0.14",` +
+				`"featureCompatibilityVersion":"6.0","fcvLagsBinary":false,"membersSameVersion":true,` +
+				`"members":[{"host":"mongo1:27017","version":"6.0.14"},{"host":"mongo2:27017","version":""}],` +
+				`"deprecatedSettings":[]}`,
+			false,
+		},
+		{
+			"+mongos",
+			mongos,
+			`{"status":"pass","reasons":[],"binaryVersion":"6.0.14","featureCompatibilityVersion":"6.0",` +
+				`"fcvLagsBinary":false,"membersSameVersion":true,"members":[],"deprecatedSettings":[]}`,
+			false,
+		},
+		{
+			"-badVersion",
+			newReadinessMock("unknown", "6.0", bson.M{}, nil),
+			"",
+			true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := UpgradeReadinessHandler(context.Background(), tt.conn, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UpgradeReadinessHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("UpgradeReadinessHandler() = %s", diff)
+			}
+		})
+	}
+}
+
+func TestUpgradeReadinessHandler_peers(t *testing.T) {
+	t.Parallel()
+
+	members := bson.A{
+		bson.M{"name": "mongo1:27017", "self": true, "state": 1},
+		bson.M{"name": "mongo2:27017", "state": 2},
+		bson.M{"name": "mongo3:27017", "state": 2},
+		bson.M{"name": "arbiter:27017", "state": memberStateArbiter},
+	}
+
+	// A member which never answers must not use up the time of the others, and the arbiter must not be
+	// connected to at all.
+	slow := newReadinessMock("6.0.14", "6.0", bson.M{}, nil)
+	slow.dbs["admin"].BlockRun = true
+
+	conn := newReadinessMock("6.0.14", "6.0", bson.M{}, members)
+	conn.peers = map[string]*MockConn{
+		"mongo2:27017": slow,
+		"mongo3:27017": newReadinessMock("6.0.14", "6.0", bson.M{}, nil),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	got, err := UpgradeReadinessHandler(ctx, conn, nil)
+	if err != nil {
+		t.Fatalf("UpgradeReadinessHandler() error = %v", err)
+	}
+
+	want := `{"status":"warn","reasons":["cannot get version of member mongo2:27017: ` +
+		`Failed to run buildInfo command: context deadline exceeded."],"binaryVersion":"6.0.14",` +
+		`"featureCompatibilityVersion":"6.0","fcvLagsBinary":false,"membersSameVersion":true,"members":[{"host":"mongo1:27017","version":"6.0.14"},` +
+		`{"host":"mongo2:27017","version":""},{"host":"mongo3:27017","version":"6.0.14"}],"deprecatedSettings":[]}`
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("UpgradeReadinessHandler() = %s", diff)
+	}
+
+	if ctx.Err() != nil {
+		t.Fatalf("UpgradeReadinessHandler() used up the time of the request")
+	}
+
+	if held := conn.held.Load(); held != 0 {
+		t.Fatalf("UpgradeReadinessHandler() did not give back %d peer sessions", held)
+	}
+}
+
+func Test_isNotReplSetMember(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"+standalone", errors.New("(NoReplicationEnabled) not running with --replSet"), true},
+		{"+commandNotFound", mongo.CommandError{Code: 59, Name: "CommandNotFound"}, true},
+		{"+mongos", errors.New("replSetGetStatus is not supported through mongos"), true},
+		{"-unauthorized", mongo.CommandError{Code: 13, Name: "Unauthorized"}, false},
+		{"-timeout", context.DeadlineExceeded, false},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := isNotReplSetMember(tt.err); got != tt.want {
+				t.Fatalf("isNotReplSetMember() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,25 +16,175 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"golang.zabbix.com/sdk/zbxerr"
 )
 
+const stateKeyServerVersion = "server.version"
+
 // VersionHandler executes 'buildInfo' command extracting and returning version
 // info from the response.
-func VersionHandler(ctx context.Context, s Session, _ map[string]string) (any, error) {
+func VersionHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
+	version, err := getVersion(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+
+	return version, nil
+}
+
+func getVersion(ctx context.Context, s Session) (string, error) {
 	buildInfo := bson.M{}
 
 	err := s.DB("admin").Run(ctx, &bson.D{{Key: "buildInfo", Value: 1}}, &buildInfo)
 	if err != nil {
-		return nil, zbxerr.New("failed to run buildInfo command").Wrap(err)
+		return "", zbxerr.New("failed to run buildInfo command").Wrap(err)
 	}
 
-	version, ok := buildInfo["version"]
+	version, ok := buildInfo["version"].(string)
 	if !ok {
-		return nil, zbxerr.New("version not found in buildInfo")
+		return "", zbxerr.New("version not found in buildInfo")
 	}
 
 	return version, nil
 }
+
+// cachedServerVersion returns the parsed version of the server, running buildInfo only on the first poll of
+// the connection. Failures are not cached, so the next poll tries again.
+func cachedServerVersion(ctx context.Context, s Session) (serverVersion, error) {
+	if v, ok := s.State().Load(stateKeyServerVersion); ok {
+		return v.(serverVersion), nil //nolint:forcetypeassert
+	}
+
+	version, err := getVersion(ctx, s)
+	if err != nil {
+		return serverVersion{}, err
+	}
+
+	v, err := parseVersion(version)
+	if err != nil {
+		return serverVersion{}, err
+	}
+
+	s.State().Swap(stateKeyServerVersion, v)
+
+	return v, nil
+}
+
+type buildInfo struct {
+	Version        string   `bson:"version"`
+	GitVersion     string   `bson:"gitVersion"`
+	Modules        []string `bson:"modules"`
+	Allocator      string   `bson:"allocator"`
+	StorageEngines []string `bson:"storageEngines"`
+	Debug          bool     `bson:"debug"`
+	OpenSSL        struct {
+		Running  string `bson:"running"`
+		Compiled string `bson:"compiled"`
+	} `bson:"openssl"`
+}
+
+type versionDetails struct {
+	Version         string   `json:"version"`
+	Major           int      `json:"major"`
+	Minor           int      `json:"minor"`
+	Patch           int      `json:"patch"`
+	VersionNumber   int      `json:"versionNumber"`
+	Edition         string   `json:"edition"`
+	GitVersion      string   `json:"gitVersion"`
+	OpenSSLRunning  string   `json:"opensslRunning"`
+	OpenSSLCompiled string   `json:"opensslCompiled"`
+	Allocator       string   `json:"allocator"`
+	StorageEngines  []string `json:"storageEngines"`
+	Debug           bool     `json:"debug"`
+}
+
+// VersionDetailsHandler executes 'buildInfo' command and returns the version split into components,
+// the comparable version number and the build details.
+func VersionDetailsHandler(ctx context.Context, s Session, _ map[string]string, _ ...string) (any, error) {
+	var info buildInfo
+
+	err := s.DB("admin").Run(ctx, &bson.D{{Key: "buildInfo", Value: 1}}, &info)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	v, err := parseVersion(info.Version)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotParseResult.Wrap(err)
+	}
+
+	res := versionDetails{
+		Version:         info.Version,
+		Major:           v.major,
+		Minor:           v.minor,
+		Patch:           v.patch,
+		VersionNumber:   v.number(),
+		Edition:         "community",
+		GitVersion:      info.GitVersion,
+		OpenSSLRunning:  info.OpenSSL.Running,
+		OpenSSLCompiled: info.OpenSSL.Compiled,
+		Allocator:       info.Allocator,
+		StorageEngines:  info.StorageEngines,
+		Debug:           info.Debug,
+	}
+
+	for _, m := range info.Modules {
+		if m == "enterprise" {
+			res.Edition = "enterprise"
+		}
+	}
+
+	if res.StorageEngines == nil {
+		res.StorageEngines = []string{}
+	}
+
+	jsonRes, err := json.Marshal(res)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
+
+type serverVersion struct {
+	major int
+	minor int
+	patch int
+}
+
+// parseVersion parses a version string like "6.0.14" or "7.0.0-rc1", ignoring pre-release suffixes.
+func parseVersion(v string) (serverVersion, error) {
+	var (
+		res   serverVersion
+		parts = strings.SplitN(strings.SplitN(v, "-", splitCount)[0], ".", 3)
+		nums  = []*int{&res.major, &res.minor, &res.patch}
+	)
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return serverVersion{}, fmt.Errorf("invalid version %q", v)
+		}
+
+		*nums[i] = n
+	}
+
+	return res, nil
+}
+
+// release returns the major.minor release series, the granularity of feature compatibility versions.
+func (v serverVersion) release() string {
+	return fmt.Sprintf("%d.%d", v.major, v.minor)
+}
+
+// number returns the version encoded as major*1000000 + minor*1000 + patch, so 6.0.14 becomes 6000014
+// and versions can be compared as integers.
+func (v serverVersion) number() int {
+	return v.major*1000000 + v.minor*1000 + v.patch
+}
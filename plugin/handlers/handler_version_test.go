@@ -112,3 +112,71 @@ func TestVersionHandler(t *testing.T) {
 		})
 	}
 }
+
+func TestVersionDetailsHandler(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		resp    bson.M
+		want    any
+		wantErr bool
+	}{
+		{
+			"+enterprise",
+			bson.M{
+				"version":        "6.0.14",
+				"gitVersion":     "25225db95574916fecab3af75b184409f8713aef",
+				"modules":        bson.A{"enterprise"},
+				"allocator":      "tcmalloc",
+				"storageEngines": bson.A{"devnull", "inMemory", "wiredTiger"},
+				"debug":          false,
+				"openssl":        bson.M{"running": "OpenSSL 3.0.2", "compiled": "OpenSSL 3.0.2"},
+			},
+			`{"version":"6.0.14","major":6,"minor":0,"patch":14,"versionNumber":6000014,` +
+				`"edition":"enterprise","gitVersion":"25225db95574916fecab3af75b184409f8713aef",` +
+				`"opensslRunning":"OpenSSL 3.0.2","opensslCompiled":"OpenSSL 3.0.2","allocator":"tcmalloc",` +
+				`"storageEngines":["devnull","inMemory","wiredTiger"],"debug":false}`,
+			false,
+		},
+		{
+			"+communityPreRelease",
+			bson.M{"version": "7.0.0-rc1", "modules": bson.A{}, "debug": true},
+			`{"version":"7.0.0-rc1","major":7,"minor":0,"patch":0,"versionNumber":7000000,` +
+				`"edition":"community","gitVersion":"","opensslRunning":"","opensslCompiled":"",` +
+				`"allocator":"","storageEngines":[],"debug":true}`,
+			false,
+		},
+		{
+			"-invalidVersion",
+			bson.M{"version": "unknown"},
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			mockSess := &MockConn{
+				dbs: map[string]*MockMongoDatabase{
+					"admin": {
+						RunFunc: func(_, _ string) ([]byte, error) {
+							return bson.Marshal(tt.resp)
+						},
+					},
+				},
+			}
+
+			got, err := VersionDetailsHandler(context.Background(), mockSess, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VersionDetailsHandler() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Fatalf("VersionDetailsHandler() = %s", diff)
+			}
+		})
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.zabbix.com/sdk/log"
 	"golang.zabbix.com/sdk/uri"
@@ -45,15 +46,30 @@ type Session interface {
 	DB(name string) Database
 	DatabaseNames(ctx context.Context) (names []string, err error)
 	Ping(ctx context.Context) error
+	State() *State
+	// Peer returns a session to another member of the deployment, reusing credentials and TLS settings.
+	// The session must be given back by calling the returned function when the request is done.
+	Peer(ctx context.Context, host string) (Session, func(), error)
 }
 
 type Database interface {
 	C(name string) Collection
 	CollectionNames(ctx context.Context) (names []string, err error)
+	// CollectionSpecs lists the collections with their type and options, which requires the
+	// listCollections privilege.
+	CollectionSpecs(ctx context.Context) (specs []CollectionSpec, err error)
 	Run(ctx context.Context, cmd, result any) error
 }
 
+// CollectionSpec describes a collection as returned by the listCollections command.
+type CollectionSpec struct {
+	Name    string `bson:"name"`
+	Type    string `bson:"type"`
+	Options bson.M `bson:"options"`
+}
+
 type Collection interface {
+	Aggregate(ctx context.Context, pipeline any, opts ...*options.AggregateOptions) (q Query, err error)
 	Find(ctx context.Context, query any, opts ...*options.FindOptions) (q Query, err error)
 	FindOne(ctx context.Context, query any, opts ...*options.FindOneOptions) Query
 }
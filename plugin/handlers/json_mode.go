@@ -0,0 +1,169 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// JSON modes of documents returned by the server.
+const (
+	JSONModeLegacy    = "legacy"
+	JSONModeCanonical = "canonical"
+	JSONModeRelaxed   = "relaxed"
+	JSONModeZabbix    = "zabbix"
+)
+
+// JSONModes lists the supported JSON modes.
+var JSONModes = []string{JSONModeLegacy, JSONModeCanonical, JSONModeRelaxed, JSONModeZabbix}
+
+// marshalDocument renders a document returned by the server, or an array of them, according to the JSON mode
+// of the request. The document is projected to the fields of the request first, so that only the selected
+// values are rendered.
+func marshalDocument(ctx context.Context, v any) ([]byte, error) {
+	if p := projectionFrom(ctx); p != nil && len(p.Paths) > 0 {
+		if doc, ok := plainValue(v); ok {
+			v = ProjectFields(doc, p.Paths)
+			p.Applied = true
+		}
+	}
+
+	return marshalMode(v, configFrom(ctx).JSONMode)
+}
+
+// marshalMode renders v in the JSON mode:
+//   - legacy uses encoding/json, losing BSON types;
+//   - canonical and relaxed use MongoDB Extended JSON v2;
+//   - zabbix uses plain JSON with dates as Unix seconds, timestamps as {"t","i"} objects,
+//     ObjectIDs and binary data as strings and exact integers and decimals.
+func marshalMode(v any, mode string) ([]byte, error) {
+	switch mode {
+	case JSONModeCanonical:
+		return marshalExtJSON(v, true)
+	case JSONModeRelaxed:
+		return marshalExtJSON(v, false)
+	case JSONModeZabbix:
+		return json.Marshal(zabbixValue(v))
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// marshalExtJSON renders v as Extended JSON. Arrays are rendered element by element because
+// Extended JSON only supports documents at the top level.
+func marshalExtJSON(v any, canonical bool) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	if _, ok := v.(primitive.D); ok || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) {
+		return bson.MarshalExtJSON(v, canonical, false)
+	}
+
+	items := make([]string, 0, rv.Len())
+
+	for i := 0; i < rv.Len(); i++ {
+		item, err := bson.MarshalExtJSON(rv.Index(i).Interface(), canonical, false)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, string(item))
+	}
+
+	return []byte("[" + strings.Join(items, ",") + "]"), nil
+}
+
+// zabbixValue converts BSON values to types encoding/json renders in the zabbix JSON mode.
+func zabbixValue(v any) any {
+	switch val := v.(type) {
+	case *bson.M:
+		return zabbixValue(*val)
+	case bson.M:
+		return zabbixDocument(val)
+	case map[string]any:
+		return zabbixDocument(val)
+	case primitive.D:
+		doc := make(map[string]any, len(val))
+		for _, e := range val {
+			doc[e.Key] = zabbixValue(e.Value)
+		}
+
+		return doc
+	case primitive.A:
+		return zabbixArray(val)
+	case []any:
+		return zabbixArray(val)
+	case []bson.M:
+		arr := make([]any, 0, len(val))
+		for _, doc := range val {
+			arr = append(arr, zabbixDocument(doc))
+		}
+
+		return arr
+	case primitive.DateTime:
+		return int64(val) / 1000
+	case primitive.Timestamp:
+		return map[string]uint32{"t": val.T, "i": val.I}
+	case primitive.ObjectID:
+		return val.Hex()
+	case primitive.Decimal128:
+		return decimalNumber(val)
+	case primitive.Binary:
+		return base64.StdEncoding.EncodeToString(val.Data)
+	case primitive.Regex:
+		return fmt.Sprintf("/%s/%s", val.Pattern, val.Options)
+	case primitive.Null, primitive.Undefined:
+		return nil
+	default:
+		return v
+	}
+}
+
+func zabbixDocument(doc map[string]any) map[string]any {
+	res := make(map[string]any, len(doc))
+	for k, v := range doc {
+		res[k] = zabbixValue(v)
+	}
+
+	return res
+}
+
+func zabbixArray(arr []any) []any {
+	res := make([]any, 0, len(arr))
+	for _, v := range arr {
+		res = append(res, zabbixValue(v))
+	}
+
+	return res
+}
+
+// decimalNumber renders a finite decimal as an exact JSON number, and NaN and infinities as strings.
+func decimalNumber(d primitive.Decimal128) any {
+	s := d.String()
+
+	n := json.Number(s)
+
+	if _, err := json.Marshal(n); err != nil {
+		return s
+	}
+
+	return n
+}
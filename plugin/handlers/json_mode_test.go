@@ -0,0 +1,171 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_marshalMode(t *testing.T) {
+	t.Parallel()
+
+	oid, err := primitive.ObjectIDFromHex("5f1e8c0b9d3e2a0001a1b2c3")
+	if err != nil {
+		t.Fatalf("failed to parse ObjectID: %v", err)
+	}
+
+	dec, err := primitive.ParseDecimal128("12345678901234567890.5")
+	if err != nil {
+		t.Fatalf("failed to parse decimal: %v", err)
+	}
+
+	doc := bson.M{
+		"big":  int64(9007199254740993),
+		"date": primitive.DateTime(1700000000123),
+		"dec":  dec,
+		"id":   oid,
+		"ts":   primitive.Timestamp{T: 1700000000, I: 3},
+		"arr":  primitive.A{int32(1), primitive.D{{Key: "x", Value: primitive.Null{}}}},
+	}
+
+	tests := []struct {
+		name    string
+		v       any
+		mode    string
+		want    string
+		wantErr bool
+	}{
+		{
+			"+canonical",
+			bson.D{{Key: "big", Value: int64(9007199254740993)}, {Key: "ts", Value: primitive.Timestamp{T: 1, I: 2}}},
+			JSONModeCanonical,
+			`{"big":{"$numberLong":"9007199254740993"},"ts":{"$timestamp":{"t":1,"i":2}}}`,
+			false,
+		},
+		{
+			"+relaxed",
+			bson.D{{Key: "big", Value: int64(9007199254740993)}, {Key: "date", Value: primitive.DateTime(0)}},
+			JSONModeRelaxed,
+			`{"big":9007199254740993,"date":{"$date":"1970-01-01T00:00:00Z"}}`,
+			false,
+		},
+		{
+			"+relaxedArray",
+			[]bson.M{{"a": int32(1)}, {"b": "x"}},
+			JSONModeRelaxed,
+			`[{"a":1},{"b":"x"}]`,
+			false,
+		},
+		{
+			"+zabbix",
+			&doc,
+			JSONModeZabbix,
+			`{"arr":[1,{"x":null}],"big":9007199254740993,"date":1700000000,"dec":12345678901234567890.5,` +
+				`"id":"5f1e8c0b9d3e2a0001a1b2c3","ts":{"i":3,"t":1700000000}}`,
+			false,
+		},
+		{"+legacy", bson.M{"a": int32(1)}, JSONModeLegacy, `{"a":1}`, false},
+		{"-canonicalNotDocument", 1, JSONModeCanonical, "", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := marshalMode(tt.v, tt.mode)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("marshalMode() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Fatalf("marshalMode() = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_marshalDocument_projection(t *testing.T) {
+	t.Parallel()
+
+	doc := bson.M{
+		"date": primitive.DateTime(1700000000123),
+		"mem":  primitive.D{{Key: "resident", Value: int64(85)}, {Key: "virtual", Value: int64(1024)}},
+		"arr":  primitive.A{int32(1), primitive.D{{Key: "x", Value: primitive.Timestamp{T: 1, I: 2}}}},
+	}
+
+	tests := []struct {
+		name        string
+		v           any
+		mode        string
+		paths       []FieldPath
+		want        string
+		wantApplied bool
+	}{
+		{
+			"+zabbix",
+			&doc,
+			JSONModeZabbix,
+			[]FieldPath{{"date"}, {"mem", "resident"}, {"arr", "1", "x"}},
+			`{"arr":[{"x":{"i":2,"t":1}}],"date":1700000000,"mem":{"resident":85}}`,
+			true,
+		},
+		{
+			"+canonical",
+			doc,
+			JSONModeCanonical,
+			[]FieldPath{{"mem", "resident"}},
+			`{"mem":{"resident":{"$numberLong":"85"}}}`,
+			true,
+		},
+		{
+			"+array",
+			[]bson.M{{"a": int32(1), "b": "x"}, {"a": int32(2)}},
+			JSONModeLegacy,
+			[]FieldPath{{"*", "a"}},
+			`[{"a":1},{"a":2}]`,
+			true,
+		},
+		{"+noPaths", bson.M{"a": int32(1)}, JSONModeLegacy, nil, `{"a":1}`, false},
+		{"+notDocument", struct{ A int }{1}, JSONModeLegacy, []FieldPath{{"B"}}, `{"A":1}`, false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			proj := &Projection{Paths: tt.paths}
+			ctx := WithProjection(WithConfig(context.Background(), &Config{JSONMode: tt.mode}), proj)
+
+			got, err := marshalDocument(ctx, tt.v)
+			if err != nil {
+				t.Fatalf("marshalDocument() error = %v", err)
+			}
+
+			if diff := cmp.Diff(tt.want, string(got)); diff != "" {
+				t.Fatalf("marshalDocument() = %s", diff)
+			}
+
+			if proj.Applied != tt.wantApplied {
+				t.Fatalf("Applied = %v, want %v", proj.Applied, tt.wantApplied)
+			}
+		})
+	}
+}
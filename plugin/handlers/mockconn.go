@@ -18,8 +18,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"reflect"
+	"sync/atomic"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"golang.zabbix.com/sdk/zbxerr"
 )
@@ -30,7 +33,11 @@ const (
 )
 
 type MockConn struct {
-	dbs map[string]*MockMongoDatabase
+	dbs   map[string]*MockMongoDatabase
+	state *State
+	peers map[string]*MockConn
+	// held is the number of peer sessions which were not given back yet.
+	held atomic.Int32
 }
 
 func NewMockConn() *MockConn {
@@ -72,16 +79,42 @@ func (*MockConn) Ping(_ context.Context) error {
 	return nil
 }
 
+// State returns the mock connection state, creating it on first use.
+func (conn *MockConn) State() *State {
+	if conn.state == nil {
+		conn.state = NewState()
+	}
+
+	return conn.state
+}
+
+// Peer returns a mock session registered for the host.
+func (conn *MockConn) Peer(_ context.Context, host string) (Session, func(), error) { //nolint:ireturn
+	peer, ok := conn.peers[host]
+	if !ok {
+		return nil, nil, zbxerr.ErrorConnectionFailed
+	}
+
+	conn.held.Add(1)
+
+	return peer, func() { conn.held.Add(-1) }, nil
+}
+
 type MockSession interface {
 	DB(name string) Database
 	DatabaseNames(ctx context.Context) ([]string, error)
 	Ping(_ context.Context) error
+	State() *State
+	Peer(ctx context.Context, host string) (Session, func(), error)
 }
 
 type MockMongoDatabase struct {
 	name        string
 	collections map[string]*MockMongoCollection
 	RunFunc     func(dbName, cmd string) ([]byte, error)
+	SpecsFunc   func(ctx context.Context) ([]CollectionSpec, error)
+	// BlockRun makes commands wait until the context is done, like a server which never answers.
+	BlockRun bool
 }
 
 func (d *MockMongoDatabase) C(name string) Collection {
@@ -112,8 +145,33 @@ func (d *MockMongoDatabase) CollectionNames(_ context.Context) ([]string, error)
 	return names, nil
 }
 
+// CollectionSpecs returns specs of all collections with the "collection" type, unless SpecsFunc is set.
+func (d *MockMongoDatabase) CollectionSpecs(ctx context.Context) ([]CollectionSpec, error) {
+	if d.SpecsFunc != nil {
+		return d.SpecsFunc(ctx)
+	}
+
+	names, err := d.CollectionNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	specs := make([]CollectionSpec, 0, len(names))
+	for _, name := range names {
+		specs = append(specs, CollectionSpec{Name: name, Type: "collection"})
+	}
+
+	return specs, nil
+}
+
 // Run executed command with given mock function.
-func (d *MockMongoDatabase) Run(_ context.Context, cmd, result any) error {
+func (d *MockMongoDatabase) Run(ctx context.Context, cmd, result any) error {
+	if d.BlockRun {
+		<-ctx.Done()
+
+		return ctx.Err()
+	}
+
 	if d.RunFunc == nil {
 		d.RunFunc = func(dbName, _ string) ([]byte, error) {
 			if dbName == mustFail {
@@ -144,6 +202,31 @@ type MockMongoCollection struct {
 	queries map[any]*MockMongoQuery
 }
 
+// Aggregate retrieves documents returned by the pipeline.
+//
+//nolint:ireturn,nolintlint
+func (c *MockMongoCollection) Aggregate(
+	_ context.Context,
+	pipeline any,
+	_ ...*options.AggregateOptions,
+) (Query, error) {
+	if c.name == mustFail {
+		return nil, errors.New("fail")
+	}
+
+	queryHash := fmt.Sprintf("%v", pipeline)
+	if q, ok := c.queries[queryHash]; ok {
+		return q, nil
+	}
+
+	c.queries[queryHash] = &MockMongoQuery{
+		collection: c.name,
+		query:      pipeline,
+	}
+
+	return c.queries[queryHash], nil
+}
+
 // Find retrieves documents matching query.
 //
 //nolint:ireturn,nolintlint
@@ -214,9 +297,23 @@ func (*MockMongoQuery) Count(_ context.Context) (int, error) {
 	return 1, nil
 }
 
-// Get mock function, retrieves fake result.
+// Get mock function, retrieves fake result. For slice results DataFunc must return
+// an array encoded as a document with "0", "1", ... keys.
 func (q *MockMongoQuery) Get(_ context.Context, result any) error {
-	return q.retrieve(result)
+	if result == nil || reflect.ValueOf(result).Elem().Kind() != reflect.Slice {
+		return q.retrieve(result)
+	}
+
+	if q.DataFunc == nil {
+		return errNotFound
+	}
+
+	data, err := q.DataFunc()
+	if err != nil {
+		return err
+	}
+
+	return bson.RawValue{Type: bsontype.Array, Value: data}.Unmarshal(result)
 }
 
 // GetSingle mock function, retrieves fake single result.
@@ -0,0 +1,66 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+// Server roles returned by ServerRole.
+const (
+	RolePrimary    = "primary"
+	RoleSecondary  = "secondary"
+	RoleArbiter    = "arbiter"
+	RoleMongos     = "mongos"
+	RoleStandalone = "standalone"
+	RoleOther      = "other"
+)
+
+type isMasterReply struct {
+	IsMaster    bool   `bson:"ismaster"`
+	Secondary   bool   `bson:"secondary"`
+	ArbiterOnly bool   `bson:"arbiterOnly"`
+	SetName     string `bson:"setName"`
+	Msg         string `bson:"msg"`
+}
+
+// ServerRole returns the role of the server in the deployment, as reported by the 'isMaster' command,
+// which unlike 'hello' is supported by all server versions.
+func ServerRole(ctx context.Context, s Session) (string, error) {
+	var reply isMasterReply
+
+	err := s.DB("admin").Run(ctx, &bson.D{{Key: "isMaster", Value: 1}}, &reply)
+	if err != nil {
+		return "", zbxerr.ErrorCannotFetchData.Wrap(err)
+	}
+
+	switch {
+	case reply.Msg == "isdbgrid":
+		return RoleMongos, nil
+	case reply.SetName == "":
+		return RoleStandalone, nil
+	case reply.IsMaster:
+		return RolePrimary, nil
+	case reply.Secondary:
+		return RoleSecondary, nil
+	case reply.ArbiterOnly:
+		return RoleArbiter, nil
+	default:
+		return RoleOther, nil
+	}
+}
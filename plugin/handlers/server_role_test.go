@@ -0,0 +1,73 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestServerRole(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		reply   bson.M
+		err     error
+		want    string
+		wantErr bool
+	}{
+		{"+mongos", bson.M{"ismaster": true, "msg": "isdbgrid"}, nil, RoleMongos, false},
+		{"+standalone", bson.M{"ismaster": true}, nil, RoleStandalone, false},
+		{"+primary", bson.M{"ismaster": true, "setName": "rs0"}, nil, RolePrimary, false},
+		{"+secondary", bson.M{"secondary": true, "setName": "rs0"}, nil, RoleSecondary, false},
+		{"+arbiter", bson.M{"arbiterOnly": true, "setName": "rs0"}, nil, RoleArbiter, false},
+		{"+other", bson.M{"setName": "rs0"}, nil, RoleOther, false},
+		{"-commandErr", nil, errors.New("fail"), "", true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			conn := &MockConn{
+				dbs: map[string]*MockMongoDatabase{
+					"admin": {
+						RunFunc: func(_, _ string) ([]byte, error) {
+							if tt.err != nil {
+								return nil, tt.err
+							}
+
+							return bson.Marshal(tt.reply)
+						},
+					},
+				},
+			}
+
+			got, err := ServerRole(context.Background(), conn)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ServerRole() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Fatalf("ServerRole() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
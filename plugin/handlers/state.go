@@ -0,0 +1,76 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// State stores values that handlers need to keep between polls of the same connection.
+type State struct {
+	mu     sync.Mutex
+	values map[string]any
+}
+
+// NewState returns an empty State.
+func NewState() *State {
+	return &State{values: make(map[string]any)}
+}
+
+// Load returns a value stored under the key.
+func (s *State) Load(key string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	v, ok := s.values[key]
+
+	return v, ok
+}
+
+// Swap stores a value under the key and returns the previous one, if any.
+func (s *State) Swap(key string, value any) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, ok := s.values[key]
+	s.values[key] = value
+
+	return prev, ok
+}
+
+// itemStateKey returns the key of a value kept per item rather than per connection: items polled with different
+// parameters on the same connection, for example with another output format, keep separate values, so that they
+// do not take each other's changes.
+func itemStateKey(key string, params map[string]string) string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var b strings.Builder
+
+	b.WriteString(key)
+
+	for _, name := range names {
+		b.WriteString("," + name + "=" + strconv.Quote(params[name]))
+	}
+
+	return b.String()
+}
@@ -0,0 +1,672 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// yamlIndent is the number of spaces nested nodes are indented with by marshalYAML.
+const yamlIndent = 2
+
+var errInvalidYAML = errors.New("invalid YAML")
+
+var (
+	yamlNumber   = regexp.MustCompile(`^[-+]?(\.[0-9]+|[0-9]+(\.[0-9]*)?)([eE][-+]?[0-9]+)?$`)
+	yamlPlainKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*$`)
+)
+
+// yamlLine is a line of a YAML document with its indentation and without comments.
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// unmarshalYAML decodes a YAML document to the same types json.Unmarshal decodes JSON to: maps with string keys,
+// slices, strings, float64 numbers, booleans and nil.
+// Block and flow mappings and sequences, plain and quoted scalars and comments are supported, while anchors,
+// tags, block scalars and multi-line plain scalars are not.
+func unmarshalYAML(data []byte) (any, error) {
+	lines, err := yamlLines(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	p := &yamlParser{lines: lines}
+
+	v, err := p.node(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+
+	return v, nil
+}
+
+// yamlLines splits a document to lines, dropping empty lines, comments and document markers.
+func yamlLines(data []byte) ([]yamlLine, error) {
+	var lines []yamlLine
+
+	for i, raw := range strings.Split(string(data), "\n") {
+		raw = strings.TrimRight(raw, " \t\r")
+
+		text := strings.TrimLeft(raw, " ")
+		if strings.HasPrefix(text, "\t") {
+			return nil, fmt.Errorf("%w: line %d: tabs are not allowed in indentation", errInvalidYAML, i+1)
+		}
+
+		text = strings.TrimRight(stripYAMLComment(text), " \t")
+		if text == "" || text == "---" || text == "..." || strings.HasPrefix(text, "%") {
+			continue
+		}
+
+		lines = append(lines, yamlLine{num: i + 1, indent: len(raw) - len(strings.TrimLeft(raw, " ")), text: text})
+	}
+
+	return lines, nil
+}
+
+// stripYAMLComment removes a comment starting with # at the beginning of the text or after a space,
+// outside of quoted scalars.
+func stripYAMLComment(text string) string {
+	var quote byte
+
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+
+		switch {
+		case quote == '"' && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case (c == '"' || c == '\'') && (i == 0 || strings.IndexByte(" [{,:", text[i-1]) >= 0):
+			quote = c
+		case c == '#' && (i == 0 || text[i-1] == ' ' || text[i-1] == '\t'):
+			return text[:i]
+		}
+	}
+
+	return text
+}
+
+// node parses the block node starting at the current line with the given indentation.
+func (p *yamlParser) node(indent int) (any, error) {
+	line := p.lines[p.pos]
+	if line.indent != indent {
+		return nil, p.errorf("unexpected indentation")
+	}
+
+	if isYAMLSeqItem(line.text) {
+		return p.sequence(indent)
+	}
+
+	if _, _, ok := splitYAMLKey(line.text); ok {
+		return p.mapping(indent)
+	}
+
+	v, err := parseYAMLScalar(line.text)
+	if err != nil {
+		return nil, p.errorf("%s", err)
+	}
+
+	p.pos++
+
+	return v, nil
+}
+
+func (p *yamlParser) mapping(indent int) (map[string]any, error) {
+	m := make(map[string]any)
+
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		rawKey, value, ok := splitYAMLKey(p.lines[p.pos].text)
+		if !ok || isYAMLSeqItem(p.lines[p.pos].text) {
+			return nil, p.errorf("mapping key expected")
+		}
+
+		key, err := parseYAMLKey(rawKey)
+		if err != nil {
+			return nil, p.errorf("%s", err)
+		}
+
+		if _, dup := m[key]; dup {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+
+		p.pos++
+
+		m[key], err = p.value(indent, value, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (p *yamlParser) sequence(indent int) ([]any, error) {
+	s := []any{}
+
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSeqItem(p.lines[p.pos].text) {
+		text := strings.TrimLeft(strings.TrimPrefix(p.lines[p.pos].text, "-"), " ")
+		if text == "" {
+			p.pos++
+
+			v, err := p.value(indent, "", false)
+			if err != nil {
+				return nil, err
+			}
+
+			s = append(s, v)
+
+			continue
+		}
+
+		// A node on the line of its item is parsed as if it started on a line of its own,
+		// indented by the item indicator.
+		p.lines[p.pos].indent += len(p.lines[p.pos].text) - len(text)
+		p.lines[p.pos].text = text
+
+		if _, _, ok := splitYAMLKey(text); !ok && !isYAMLSeqItem(text) {
+			v, err := parseYAMLScalar(text)
+			if err != nil {
+				return nil, p.errorf("%s", err)
+			}
+
+			p.pos++
+			s = append(s, v)
+
+			continue
+		}
+
+		v, err := p.node(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+
+		s = append(s, v)
+	}
+
+	return s, nil
+}
+
+// value parses the value of a mapping key or of a sequence item: the inline text if set, otherwise a block node
+// on the following lines. A sequence may be indented the same as the key it is the value of.
+func (p *yamlParser) value(indent int, text string, sameIndentSeq bool) (any, error) {
+	if text != "" {
+		v, err := parseYAMLScalar(text)
+		if err != nil {
+			return nil, p.errorfAt(p.pos-1, "%s", err)
+		}
+
+		return v, nil
+	}
+
+	if p.pos == len(p.lines) {
+		return nil, nil
+	}
+
+	next := p.lines[p.pos]
+
+	switch {
+	case next.indent > indent:
+		return p.node(next.indent)
+	case sameIndentSeq && next.indent == indent && isYAMLSeqItem(next.text):
+		return p.sequence(indent)
+	default:
+		return nil, nil
+	}
+}
+
+func (p *yamlParser) errorf(format string, args ...any) error {
+	return p.errorfAt(p.pos, format, args...)
+}
+
+func (p *yamlParser) errorfAt(pos int, format string, args ...any) error {
+	if pos >= len(p.lines) {
+		return fmt.Errorf("%w: %s at the end of the document", errInvalidYAML, fmt.Sprintf(format, args...))
+	}
+
+	return fmt.Errorf("%w: line %d: %s", errInvalidYAML, p.lines[pos].num, fmt.Sprintf(format, args...))
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits a "key: value" line, the key may be quoted.
+func splitYAMLKey(text string) (string, string, bool) {
+	if text == "" || text[0] == '[' || text[0] == '{' {
+		return "", "", false
+	}
+
+	start := 0
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 {
+			return "", "", false
+		}
+
+		start = end + 1
+	}
+
+	for i := start; i < len(text); i++ {
+		if text[i] == ':' && (i == len(text)-1 || text[i+1] == ' ') {
+			return strings.TrimSpace(text[:i]), strings.TrimSpace(text[i+1:]), true
+		}
+	}
+
+	return "", "", false
+}
+
+func parseYAMLKey(raw string) (string, error) {
+	if raw == "" {
+		return "", errors.New("empty key")
+	}
+
+	if raw[0] == '"' || raw[0] == '\'' {
+		return unquoteYAML(raw)
+	}
+
+	return raw, nil
+}
+
+// parseYAMLScalar parses an inline value: a flow collection, a quoted or a plain scalar.
+func parseYAMLScalar(text string) (any, error) {
+	switch text[0] {
+	case '[', '{':
+		f := &yamlFlow{text: text}
+
+		v, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+
+		f.skipSpaces()
+
+		if f.pos != len(f.text) {
+			return nil, fmt.Errorf("unexpected %q after a flow collection", f.text[f.pos:])
+		}
+
+		return v, nil
+	case '"', '\'':
+		if closingQuote(text) != len(text)-1 {
+			return nil, fmt.Errorf("unexpected text after a quoted scalar %s", text)
+		}
+
+		return unquoteYAML(text)
+	case '&', '*', '!', '|', '>', '@', '`':
+		return nil, fmt.Errorf("unsupported YAML syntax %s", text)
+	}
+
+	return plainYAMLScalar(text), nil
+}
+
+// plainYAMLScalar resolves a plain scalar to null, a boolean, a number or a string.
+func plainYAMLScalar(text string) any {
+	switch text {
+	case "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+
+	if yamlNumber.MatchString(text) {
+		f, err := strconv.ParseFloat(text, 64)
+		if err == nil {
+			return f
+		}
+	}
+
+	return text
+}
+
+// closingQuote returns the index of the quote closing the quoted scalar the text starts with, or -1.
+func closingQuote(text string) int {
+	quote := text[0]
+
+	for i := 1; i < len(text); i++ {
+		switch {
+		case quote == '"' && text[i] == '\\':
+			i++
+		case text[i] != quote:
+		case quote == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		default:
+			return i
+		}
+	}
+
+	return -1
+}
+
+// unquoteYAML decodes a single or double quoted scalar. Escapes of double quoted scalars are decoded
+// the same way as in JSON strings.
+func unquoteYAML(text string) (string, error) {
+	if text[0] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+
+	var s string
+
+	err := json.Unmarshal([]byte(text), &s)
+	if err != nil {
+		return "", fmt.Errorf("invalid double quoted scalar %s", text)
+	}
+
+	return s, nil
+}
+
+// yamlFlow parses flow collections such as [a, b] and {a: 1, b: [2, 3]}.
+type yamlFlow struct {
+	text string
+	pos  int
+}
+
+func (f *yamlFlow) value() (any, error) {
+	f.skipSpaces()
+
+	if f.pos == len(f.text) {
+		return nil, errors.New("unexpected end of a flow collection")
+	}
+
+	switch c := f.text[f.pos]; c {
+	case '[':
+		return f.sequence()
+	case '{':
+		return f.mapping()
+	case '"', '\'':
+		end := closingQuote(f.text[f.pos:])
+		if end < 0 {
+			return nil, errors.New("unterminated quoted scalar")
+		}
+
+		s, err := unquoteYAML(f.text[f.pos : f.pos+end+1])
+		f.pos += end + 1
+
+		return s, err
+	default:
+		start := f.pos
+		for f.pos < len(f.text) && !strings.ContainsRune(",]}", rune(f.text[f.pos])) &&
+			(f.text[f.pos] != ':' || f.pos+1 < len(f.text) && f.text[f.pos+1] != ' ') {
+			f.pos++
+		}
+
+		text := strings.TrimSpace(f.text[start:f.pos])
+		if text == "" {
+			return nil, fmt.Errorf("unexpected %q in a flow collection", c)
+		}
+
+		return parseYAMLScalar(text)
+	}
+}
+
+func (f *yamlFlow) sequence() ([]any, error) {
+	s := []any{}
+
+	f.pos++
+
+	for {
+		f.skipSpaces()
+
+		if f.consume(']') {
+			return s, nil
+		}
+
+		v, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+
+		s = append(s, v)
+
+		err = f.separator(']')
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *yamlFlow) mapping() (map[string]any, error) {
+	m := make(map[string]any)
+
+	f.pos++
+
+	for {
+		f.skipSpaces()
+
+		if f.consume('}') {
+			return m, nil
+		}
+
+		k, err := f.value()
+		if err != nil {
+			return nil, err
+		}
+
+		key, ok := k.(string)
+		if !ok {
+			key = fmt.Sprint(k)
+		}
+
+		f.skipSpaces()
+
+		if !f.consume(':') {
+			return nil, fmt.Errorf("missing value of the flow mapping key %q", key)
+		}
+
+		m[key], err = f.value()
+		if err != nil {
+			return nil, err
+		}
+
+		err = f.separator('}')
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// separator consumes a comma between flow collection entries, leaving the closing bracket to the caller.
+func (f *yamlFlow) separator(closing byte) error {
+	f.skipSpaces()
+
+	if f.consume(',') || f.pos < len(f.text) && f.text[f.pos] == closing {
+		return nil
+	}
+
+	return fmt.Errorf("missing %q in a flow collection", closing)
+}
+
+func (f *yamlFlow) consume(c byte) bool {
+	if f.pos < len(f.text) && f.text[f.pos] == c {
+		f.pos++
+
+		return true
+	}
+
+	return false
+}
+
+func (f *yamlFlow) skipSpaces() {
+	for f.pos < len(f.text) && (f.text[f.pos] == ' ' || f.text[f.pos] == '\t') {
+		f.pos++
+	}
+}
+
+// marshalYAML encodes a value decoded from JSON as a block YAML document with sorted mapping keys.
+func marshalYAML(v any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := writeYAMLNode(&buf, v, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// writeYAMLNode writes a non-empty collection as a block node, each line indented by the given number of
+// spaces, and anything else as a scalar line.
+func writeYAMLNode(buf *bytes.Buffer, v any, indent int) error {
+	pad := strings.Repeat(" ", indent)
+
+	switch t := v.(type) {
+	case map[string]any:
+		if len(t) == 0 {
+			break
+		}
+
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			buf.WriteString(pad + yamlString(k) + ":")
+
+			err := writeYAMLValue(buf, t[k], indent+yamlIndent)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case []any:
+		if len(t) == 0 {
+			break
+		}
+
+		for _, item := range t {
+			buf.WriteString(pad + "-")
+
+			err := writeYAMLValue(buf, item, indent+yamlIndent)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	s, err := yamlScalar(v)
+	if err != nil {
+		return err
+	}
+
+	buf.WriteString(pad + s + "\n")
+
+	return nil
+}
+
+// writeYAMLValue writes the value following a mapping key or a sequence item indicator.
+func writeYAMLValue(buf *bytes.Buffer, v any, indent int) error {
+	if isEmptyYAMLCollection(v) {
+		s, err := yamlScalar(v)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString(" " + s + "\n")
+
+		return nil
+	}
+
+	switch v.(type) {
+	case map[string]any, []any:
+		buf.WriteString("\n")
+
+		return writeYAMLNode(buf, v, indent)
+	}
+
+	s, err := yamlScalar(v)
+	if err != nil {
+		return err
+	}
+
+	buf.WriteString(" " + s + "\n")
+
+	return nil
+}
+
+func isEmptyYAMLCollection(v any) bool {
+	switch t := v.(type) {
+	case map[string]any:
+		return len(t) == 0
+	case []any:
+		return len(t) == 0
+	default:
+		return false
+	}
+}
+
+// yamlScalar returns the inline representation of a scalar or an empty collection. Strings that would be
+// read back as another type or need escaping are written as JSON strings, which are valid double quoted scalars.
+func yamlScalar(v any) (string, error) {
+	switch t := v.(type) {
+	case nil:
+		return "null", nil
+	case bool:
+		return strconv.FormatBool(t), nil
+	case float64:
+		data, err := json.Marshal(t)
+
+		return string(data), err
+	case map[string]any:
+		return "{}", nil
+	case []any:
+		return "[]", nil
+	case string:
+		return yamlString(t), nil
+	default:
+		return "", fmt.Errorf("%w: unsupported type %T", errInvalidYAML, v)
+	}
+}
+
+// yamlString returns a string as a plain scalar if it is read back as the same string, quoted otherwise.
+func yamlString(s string) string {
+	if yamlPlainKey.MatchString(s) && plainYAMLScalar(s) == s {
+		return s
+	}
+
+	data, _ := json.Marshal(s) //nolint:errchkjson // strings are always marshalled
+
+	return string(data)
+}
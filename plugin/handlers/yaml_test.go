@@ -0,0 +1,165 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func Test_unmarshalYAML(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{
+			"+replSetConfig",
+			`---
+# rs0 baseline
+_id: rs0
+protocolVersion: 1
+members:
+  - _id: 0
+    host: "mongo-a:27017"  # primary
+    priority: 2.5
+    tags: {dc: east, "rack": '1'}
+  - _id: 1
+    host: mongo-b:27017
+    hidden: true
+    tags: {}
+settings:
+  chainingAllowed: false
+  getLastErrorModes:
+defaultRWConcern:
+  defaultWriteConcern: {w: majority, wtimeout: 0}
+  defaultReadConcern: ~
+`,
+			`{"_id":"rs0","defaultRWConcern":{"defaultReadConcern":null,` +
+				`"defaultWriteConcern":{"w":"majority","wtimeout":0}},` +
+				`"members":[{"_id":0,"host":"mongo-a:27017","priority":2.5,"tags":{"dc":"east","rack":"1"}},` +
+				`{"_id":1,"hidden":true,"host":"mongo-b:27017","tags":{}}],` +
+				`"protocolVersion":1,"settings":{"chainingAllowed":false,"getLastErrorModes":null}}`,
+			false,
+		},
+		{
+			"+sequenceSameIndent",
+			"hosts:\n- a\n- 'it''s'\n- [1, \"x # y\", null]\n-\n  - nested\nok: 1\n",
+			`{"hosts":["a","it's",[1,"x # y",null],["nested"]],"ok":1}`,
+			false,
+		},
+		{
+			"+scalars",
+			"[true, False, ~, -1.5e3, '007', 007, 1.2.3, \"\\u00e9\"]",
+			`[true,false,null,-1500,"007",7,"1.2.3","é"]`,
+			false,
+		},
+		{"+flowJSON", `{"members": [{"_id": 0}], "ok": 1}`, `{"members":[{"_id":0}],"ok":1}`, false},
+		{"+empty", "# nothing\n", `null`, false},
+		{"-tabIndentation", "members:\n\t- a\n", "", true},
+		{"-badIndentation", "a:\n    b: 1\n  c: 2\n", "", true},
+		{"-duplicateKey", "a: 1\na: 2\n", "", true},
+		{"-anchor", "a: &x 1\n", "", true},
+		{"-blockScalar", "a: |\n  text\n", "", true},
+		{"-unclosedFlow", "a: [1, 2\n", "", true},
+		{"-unclosedQuote", "a: \"b\n", "", true},
+	}
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := unmarshalYAML([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unmarshalYAML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				if !errors.Is(err, errInvalidYAML) {
+					t.Fatalf("unmarshalYAML() error = %v, want %v", err, errInvalidYAML)
+				}
+
+				return
+			}
+
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, string(data)); diff != "" {
+				t.Fatalf("unmarshalYAML() = %s", diff)
+			}
+		})
+	}
+}
+
+func Test_marshalYAML(t *testing.T) {
+	t.Parallel()
+
+	var doc map[string]any
+
+	err := json.Unmarshal([]byte(`{"_id":"rs0","members":{"0":{"host":"a:27017","priority":1,"tags":{}}},`+
+		`"list":[{"a":1,"b":[true,null]},[],"true","1.5","x: y","",1e21,1000000],"settings":{}}`), &doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `_id: rs0
+list:
+  -
+    a: 1
+    b:
+      - true
+      - null
+  - []
+  - "true"
+  - "1.5"
+  - "x: y"
+  - ""
+  - 1e+21
+  - 1000000
+members:
+  "0":
+    host: "a:27017"
+    priority: 1
+    tags: {}
+settings: {}
+`
+
+	got, err := marshalYAML(doc)
+	if err != nil {
+		t.Fatalf("marshalYAML() error = %v", err)
+	}
+
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Fatalf("marshalYAML() = %s", diff)
+	}
+
+	back, err := unmarshalYAML(got)
+	if err != nil {
+		t.Fatalf("unmarshalYAML() error = %v", err)
+	}
+
+	if diff := cmp.Diff(any(doc), back); diff != "" {
+		t.Fatalf("unmarshalYAML(marshalYAML()) = %s", diff)
+	}
+}
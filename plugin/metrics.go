@@ -25,66 +25,144 @@ import (
 
 const (
 	keyConfigDiscovery      = "mongodb.cfg.discovery"
+	keyCollectionLatency    = "mongodb.collection.latency"
 	keyCollectionStats      = "mongodb.collection.stats"
 	keyCollectionsDiscovery = "mongodb.collections.discovery"
 	keyCollectionsUsage     = "mongodb.collections.usage"
+	keyCollectionsUsageLLD  = "mongodb.collections.usage.discovery"
+	keyCommand              = "mongodb.command"
 	keyConnPoolStats        = "mongodb.connpool.stats"
+	keyCustomQuery          = "mongodb.custom.query"
 	keyDatabaseStats        = "mongodb.db.stats"
 	keyDatabasesDiscovery   = "mongodb.db.discovery"
+	keyHostInfo             = "mongodb.host.info"
 	keyJumboChunks          = "mongodb.jumbo_chunks.count"
 	keyOplogStats           = "mongodb.oplog.stats"
+	keyParameters           = "mongodb.parameters"
+	keyParametersDrift      = "mongodb.parameters.drift"
 	keyPing                 = "mongodb.ping"
+	keyPluginStats          = "mongodb.plugin.stats"
 	keyReplSetConfig        = "mongodb.rs.config"
+	keyReplSetConfigDrift   = "mongodb.rs.config.drift"
+	keyReplSetElections     = "mongodb.rs.elections"
+	keyReplSetInitialSync   = "mongodb.rs.initialsync"
 	keyReplSetStatus        = "mongodb.rs.status"
 	keyServerStatus         = "mongodb.server.status"
 	keyShardsDiscovery      = "mongodb.sh.discovery"
+	keyTLSCert              = "mongodb.tls.cert"
+	keyUpgradeReadiness     = "mongodb.upgrade.readiness"
 	keyVersion              = "mongodb.version"
+	keyVersionDetails       = "mongodb.version.details"
 
 	uriParam        = "URI"
 	tlsConnectParam = "TLSConnect"
 	tlsCAParam      = "TLSCAFile"
 	tlsCertParam    = "TLSCertFile"
 	tlsKeyParam     = "TLSKeyFile"
+
+	tlsMinVersionParam   = "TLSMinVersion"
+	tlsCipherSuitesParam = "TLSCipherSuites"
+	tlsServerNameParam   = "TLSServerName"
+	tlsKeyPasswordParam  = "TLSKeyPassword"
+	tlsCRLParam          = "TLSCRLFile"
+
+	profileParam = "Profile"
 )
 
 var metricHandlers = map[string]handlerFunc{
+	keyCollectionLatency:    handlers.CollectionLatencyHandler,
 	keyCollectionStats:      handlers.CollectionStatsHandler,
 	keyCollectionsDiscovery: handlers.CollectionsDiscoveryHandler,
 	keyCollectionsUsage:     handlers.CollectionsUsageHandler,
+	keyCollectionsUsageLLD:  handlers.CollectionsUsageDiscoveryHandler,
+	keyCommand:              handlers.CommandHandler,
 	keyConfigDiscovery:      handlers.ConfigDiscoveryHandler,
 	keyConnPoolStats:        handlers.ConnPoolStatsHandler,
+	keyCustomQuery:          handlers.CustomQueryHandler,
 	keyDatabaseStats:        handlers.DatabaseStatsHandler,
 	keyDatabasesDiscovery:   handlers.DatabasesDiscoveryHandler,
+	keyHostInfo:             handlers.HostInfoHandler,
 	keyJumboChunks:          handlers.JumboChunksHandler,
 	keyOplogStats:           handlers.OplogStatsHandler,
+	keyParameters:           handlers.ParametersHandler,
+	keyParametersDrift:      handlers.ParametersDriftHandler,
 	keyPing:                 handlers.PingHandler,
 	keyReplSetConfig:        handlers.ReplSetConfigHandler,
+	keyReplSetConfigDrift:   handlers.ReplSetConfigDriftHandler,
+	keyReplSetElections:     handlers.ReplSetElectionsHandler,
+	keyReplSetInitialSync:   handlers.ReplSetInitialSyncHandler,
 	keyReplSetStatus:        handlers.ReplSetStatusHandler,
 	keyServerStatus:         handlers.ServerStatusHandler,
 	keyShardsDiscovery:      handlers.ShardsDiscoveryHandler,
+	keyUpgradeReadiness:     handlers.UpgradeReadinessHandler,
 	keyVersion:              handlers.VersionHandler,
+	keyVersionDetails:       handlers.VersionDetailsHandler,
 }
 
 var (
 	paramURI = metric.NewConnParam(uriParam, "URI to connect or session name.").
 			WithDefault(handlers.UriDefaults.Scheme + "://localhost:" + handlers.UriDefaults.Port).WithSession().
 			WithValidator(uri.URIValidator{Defaults: handlers.UriDefaults, AllowedSchemes: []string{"tcp"}})
-	paramUser        = metric.NewConnParam("User", "MongoDB user.")
-	paramPassword    = metric.NewConnParam("Password", "User's password.")
-	paramDatabase    = metric.NewParam("Database", "Database name.").WithDefault("admin")
-	paramCollection  = metric.NewParam("Collection", "Collection name.").SetRequired()
+	paramUser       = metric.NewConnParam("User", "MongoDB user.")
+	paramPassword   = metric.NewConnParam("Password", "User's password.")
+	paramDatabase   = metric.NewParam("Database", "Database name.").WithDefault("admin")
+	paramCollection = metric.NewParam("Collection", "Collection name.").SetRequired()
+	paramCommand    = metric.NewParam("Command", "Command document in Extended JSON.").SetRequired()
+	paramQueryCol   = metric.NewParam("Collection", "Collection name, required for aggregation queries.")
+	paramQueryName  = metric.NewParam("QueryName", "Name of the custom query file.").SetRequired()
+	paramExpected   = metric.NewParam("ExpectedFile", "Name of the expected values file.").SetRequired()
+	paramBaseline   = metric.NewParam("BaselineFile", "Name of the baseline file.").SetRequired()
+	paramInclude    = metric.NewParam("Include", "Regular expression of included names.")
+	paramExclude    = metric.NewParam("Exclude", "Regular expression of excluded names.")
+	paramDriftMode  = metric.NewParam("Mode", "Drift detection mode.").WithDefault(handlers.DriftModeCompare).
+			WithValidator(metric.SetValidator{Set: []string{handlers.DriftModeCompare, handlers.DriftModeSnapshot}})
+	paramMaxResults = metric.NewParam("MaxResults", "Maximum number of results.").WithDefault("0").
+			WithValidator(metric.NumberValidator{})
+	paramSkipSystem = metric.NewParam("SkipSystem", "Skip system databases and collections.").WithDefault("false").
+			WithValidator(metric.SetValidator{Set: []string{"true", "false"}})
+	paramPartial = metric.NewParam("Partial", "Return the results of the databases listed in time.").
+			WithDefault("false").WithValidator(metric.SetValidator{Set: []string{"true", "false"}})
+	paramUsageMode = metric.NewParam("Mode", "Output mode.").WithDefault(handlers.UsageModeRaw).
+			WithValidator(metric.SetValidator{Set: []string{handlers.UsageModeRaw, handlers.UsageModeRates}})
+	paramHistograms = metric.NewParam("Histograms", "Include latency histograms.").WithDefault("false").
+			WithValidator(metric.SetValidator{Set: []string{"true", "false"}})
 	paramTLSConnect  = metric.NewSessionOnlyParam(tlsConnectParam, "DB connection encryption type.").WithDefault("")
 	paramTLSCaFile   = metric.NewSessionOnlyParam(tlsCAParam, "TLS ca file path.").WithDefault("")
 	paramTLSCertFile = metric.NewSessionOnlyParam(tlsCertParam, "TLS cert file path.").WithDefault("")
 	paramTLSKeyFile  = metric.NewSessionOnlyParam(tlsKeyParam, "TLS key file path.").WithDefault("")
+
+	paramTLSMinVersion   = metric.NewSessionOnlyParam(tlsMinVersionParam, "Minimum TLS version.").WithDefault("")
+	paramTLSCipherSuites = metric.NewSessionOnlyParam(tlsCipherSuitesParam, "TLS cipher suites.").WithDefault("")
+	paramTLSServerName   = metric.NewSessionOnlyParam(tlsServerNameParam, "TLS server name.").WithDefault("")
+	paramTLSKeyPassword  = metric.NewSessionOnlyParam(tlsKeyPasswordParam, "TLS key password.").WithDefault("")
+	paramTLSCRLFile      = metric.NewSessionOnlyParam(tlsCRLParam, "TLS CRL file path.").WithDefault("")
+	paramProfile         = metric.NewSessionOnlyParam(profileParam, "Session profile name.").WithDefault("")
+
+	paramFormat = metric.NewParam(formatParam, "Output format.").WithDefault(formatJSON).
+			WithValidator(metric.SetValidator{Set: []string{formatJSON, formatFlat, formatLLD}})
+	paramFields = metric.NewParam(fieldsParam, "Comma separated list of returned fields.").
+			WithValidator(fieldsValidator{})
 )
 
 var metrics = metric.MetricSet{
+	keyCollectionLatency: metric.New(
+		"Returns operation counts and latencies of a given collection.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramDatabase, paramCollection, paramHistograms, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		false,
+	),
+
 	keyCollectionStats: metric.New(
 		"Returns a variety of storage statistics for a given collection.",
 		[]*metric.Param{
-			paramURI, paramUser, paramPassword, paramDatabase, paramCollection,
+			paramURI, paramUser, paramPassword, paramDatabase, paramCollection, paramFormat, paramFields,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
@@ -92,8 +170,11 @@ var metrics = metric.MetricSet{
 	keyCollectionsDiscovery: metric.New(
 		"Returns a list of discovered collections.",
 		[]*metric.Param{
-			paramURI, paramUser, paramPassword,
+			paramURI, paramUser, paramPassword, paramInclude, paramExclude, paramSkipSystem, paramMaxResults,
+			paramPartial, paramFormat, paramFields,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
@@ -101,8 +182,32 @@ var metrics = metric.MetricSet{
 	keyCollectionsUsage: metric.New(
 		"Returns usage statistics for collections.",
 		[]*metric.Param{
-			paramURI, paramUser, paramPassword,
+			paramURI, paramUser, paramPassword, paramUsageMode, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		false,
+	),
+
+	keyCollectionsUsageLLD: metric.New(
+		"Returns a list of namespaces discovered in the usage statistics.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramInclude, paramExclude, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		false,
+	),
+
+	keyCommand: metric.New(
+		"Returns the reply of an allowed read-only command.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramDatabase, paramCommand, paramFormat, paramFields,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
@@ -110,7 +215,10 @@ var metrics = metric.MetricSet{
 	keyConfigDiscovery: metric.New(
 		"Returns a list of discovered config servers.",
 		[]*metric.Param{
-			paramURI, paramUser, paramPassword, paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
@@ -119,17 +227,32 @@ var metrics = metric.MetricSet{
 		"Returns information regarding the open outgoing connections from the "+
 			"current database instance to other members of the sharded cluster or replica set.",
 		[]*metric.Param{
-			paramURI, paramUser, paramPassword,
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
 
+	keyCustomQuery: metric.New(
+		"Returns the result of a named custom aggregation pipeline or command.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramDatabase, paramQueryCol, paramQueryName,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		true,
+	),
+
 	keyDatabaseStats: metric.New(
 		"Returns statistics reflecting a given database system’s state.",
 		[]*metric.Param{
-			paramURI, paramUser, paramPassword, paramDatabase,
+			paramURI, paramUser, paramPassword, paramDatabase, paramFormat, paramFields,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
@@ -137,8 +260,22 @@ var metrics = metric.MetricSet{
 	keyDatabasesDiscovery: metric.New(
 		"Returns a list of discovered databases.",
 		[]*metric.Param{
-			paramURI, paramUser, paramPassword,
+			paramURI, paramUser, paramPassword, paramInclude, paramExclude, paramSkipSystem, paramMaxResults,
+			paramFormat, paramFields,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		false,
+	),
+
+	keyHostInfo: metric.New(
+		"Returns host hardware and operating system information combined with the effective server configuration.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
@@ -148,15 +285,41 @@ var metrics = metric.MetricSet{
 		[]*metric.Param{
 			paramURI, paramUser, paramPassword,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
 
 	keyOplogStats: metric.New(
 		"Returns a status of the replica set, using data polled from the oplog.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		false,
+	),
+
+	keyParameters: metric.New(
+		"Returns values of the given server parameters or all of them.",
 		[]*metric.Param{
 			paramURI, paramUser, paramPassword,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		true,
+	),
+
+	keyParametersDrift: metric.New(
+		"Returns server parameters which values differ from an expected values file.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramExpected, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
@@ -166,15 +329,59 @@ var metrics = metric.MetricSet{
 		[]*metric.Param{
 			paramURI, paramUser, paramPassword,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
 
+	keyPluginStats: metric.New(
+		"Returns statistics of the plugin connections and requests.",
+		[]*metric.Param{paramFormat, paramFields},
+		false,
+	),
+
 	keyReplSetConfig: metric.New(
 		"Returns a current configuration of the replica set.",
 		[]*metric.Param{
-			paramURI, paramUser, paramPassword,
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		false,
+	),
+
+	keyReplSetConfigDrift: metric.New(
+		"Returns differences between the current configuration of the replica set and a baseline file.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramBaseline, paramDriftMode, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		false,
+	),
+
+	keyReplSetElections: metric.New(
+		"Returns the number of elections since the previous poll, the current term and "+
+			"details of the last election.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		false,
+	),
+
+	keyReplSetInitialSync: metric.New(
+		"Returns the progress of an initial sync running on the replica set member.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
@@ -183,8 +390,10 @@ var metrics = metric.MetricSet{
 		"Returns a replica set status from the point of view of the member "+
 			"where the method is run.",
 		[]*metric.Param{
-			paramURI, paramUser, paramPassword,
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
@@ -192,8 +401,10 @@ var metrics = metric.MetricSet{
 	keyServerStatus: metric.New(
 		"Returns a database’s state.",
 		[]*metric.Param{
-			paramURI, paramUser, paramPassword,
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
@@ -201,8 +412,32 @@ var metrics = metric.MetricSet{
 	keyShardsDiscovery: metric.New(
 		"Returns a list of discovered shards present in the cluster.",
 		[]*metric.Param{
-			paramURI, paramUser, paramPassword,
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		false,
+	),
+
+	keyTLSCert: metric.New(
+		"Returns the server TLS certificate chain and its expiry.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		false,
+	),
+
+	keyUpgradeReadiness: metric.New(
+		"Returns readiness of the deployment for the next major version upgrade.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
@@ -212,13 +447,28 @@ var metrics = metric.MetricSet{
 		[]*metric.Param{
 			paramURI, paramUser, paramPassword,
 			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
+		},
+		false,
+	),
+
+	keyVersionDetails: metric.New(
+		"Returns database server version components and build details.",
+		[]*metric.Param{
+			paramURI, paramUser, paramPassword, paramFormat, paramFields,
+			paramTLSConnect, paramTLSCaFile, paramTLSCertFile, paramTLSKeyFile,
+			paramTLSMinVersion, paramTLSCipherSuites, paramTLSServerName, paramTLSKeyPassword, paramTLSCRLFile,
+			paramProfile,
 		},
 		false,
 	),
 }
 
 // handlerFunc defines an interface must be implemented by handlers.
-type handlerFunc func(ctx context.Context, s handlers.Session, params map[string]string) (any, error)
+type handlerFunc func(
+	ctx context.Context, s handlers.Session, params map[string]string, extraParams ...string,
+) (any, error)
 
 func init() {
 	err := plugin.RegisterMetrics(&Impl, Name, metrics.List()...)
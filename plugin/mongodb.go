@@ -17,6 +17,8 @@ package plugin
 import (
 	"context"
 	"errors"
+	"net/http"
+	"sync/atomic"
 	"time"
 
 	"golang.zabbix.com/plugin/mongodb/plugin/handlers"
@@ -37,20 +39,66 @@ var Impl Plugin
 // Plugin -
 type Plugin struct {
 	plugin.Base
-	connMgr *ConnManager
-	options PluginOptions
+	connMgr  *ConnManager
+	options  atomic.Pointer[PluginOptions]
+	stats    *requestStats
+	exporter *http.Server
+}
+
+// config returns the current options. A reload replaces the options instead of modifying them,
+// so every request takes one snapshot and uses it until it finishes.
+func (p *Plugin) config() *PluginOptions {
+	if opts := p.options.Load(); opts != nil {
+		return opts
+	}
+
+	return &PluginOptions{}
 }
 
 // Export metrics.
-//
-//nolint:gocyclo,cyclop
 func (p *Plugin) Export(key string, rawParams []string, pluginCtx plugin.ContextProvider) (any, error) {
-	params, _, hc, err := metrics[key].EvalParams(rawParams, p.options.Sessions)
+	start := time.Now()
+
+	result, err := p.export(key, rawParams, pluginCtx)
+
+	p.stats.observe(key, time.Since(start), err)
+
+	return result, err
+}
+
+func (p *Plugin) export(key string, rawParams []string, pluginCtx plugin.ContextProvider) (any, error) {
+	opts := p.config()
+
+	params, extraParams, hc, err := metrics[key].EvalParams(rawParams, opts.Sessions)
 	if err != nil {
 		return nil, err
 	}
 
-	err = metric.SetDefaults(params, hc, p.options.Default)
+	paths, err := handlers.ParseFields(params[fieldsParam])
+	if err != nil {
+		return nil, zbxerr.ErrorInvalidParams.Wrap(err)
+	}
+
+	proj := &handlers.Projection{Paths: paths}
+
+	result, err := p.exportMetric(key, params, extraParams, hc, pluginCtx, opts, proj)
+	if err != nil {
+		return nil, err
+	}
+
+	return formatOutput(result, proj, params[formatParam])
+}
+
+//nolint:gocyclo,cyclop
+func (p *Plugin) exportMetric(
+	key string, params map[string]string, extraParams []string, hc map[string]bool, pluginCtx plugin.ContextProvider,
+	opts *PluginOptions, proj *handlers.Projection,
+) (any, error) {
+	if key == keyPluginStats {
+		return p.pluginStats()
+	}
+
+	err := metric.SetDefaults(params, hc, opts.Default)
 	if err != nil {
 		return nil, err
 	}
@@ -60,6 +108,11 @@ func (p *Plugin) Export(key string, rawParams []string, pluginCtx plugin.Context
 		return nil, err
 	}
 
+	// The certificate check only needs a TLS handshake, so it must not depend on a working MongoDB session.
+	if key == keyTLSCert {
+		return p.exportTLSCert(*uri, params, pluginCtx, opts)
+	}
+
 	handleMetric := getHandlerFunc(key)
 	if handleMetric == nil {
 		return nil, zbxerr.ErrorUnsupportedMetric
@@ -80,6 +133,8 @@ func (p *Plugin) Export(key string, rawParams []string, pluginCtx plugin.Context
 		return nil, err
 	}
 
+	defer p.connMgr.Release(conn)
+
 	p.Debugf("Params: %v", params)
 
 	timeout := conn.getTimeout()
@@ -88,17 +143,19 @@ func (p *Plugin) Export(key string, rawParams []string, pluginCtx plugin.Context
 		timeout = time.Second * time.Duration(pluginCtx.Timeout())
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx := handlers.WithProjection(handlers.WithConfig(context.Background(), opts.handlersConfig()), proj)
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
-	result, err := handleMetric(ctx, conn, params)
+	result, err := handleMetric(ctx, conn, params, extraParams...)
 	if err != nil {
 		p.Errf(err.Error())
 
 		ctxErr := ctx.Err()
 
 		if ctxErr != nil && errors.Is(ctxErr, context.DeadlineExceeded) {
-			return nil, errs.New("request execution timeout exceeded")
+			return nil, errRequestTimeout
 		}
 
 		return nil, errs.Wrap(err, "failed to run command")
@@ -107,12 +164,55 @@ func (p *Plugin) Export(key string, rawParams []string, pluginCtx plugin.Context
 	return result, err
 }
 
+func (p *Plugin) exportTLSCert(
+	u uri.URI, params map[string]string, pluginCtx plugin.ContextProvider, opts *PluginOptions,
+) (any, error) {
+	timeout := time.Duration(opts.Timeout) * time.Second
+
+	if timeout < time.Second*time.Duration(pluginCtx.Timeout()) {
+		timeout = time.Second * time.Duration(pluginCtx.Timeout())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	serverName := u.Host()
+	if params[tlsServerNameParam] != "" {
+		serverName = params[tlsServerNameParam]
+	}
+
+	cfg, err := certCheckConfig(serverName, params)
+	if err != nil {
+		p.Errf(err.Error())
+
+		return nil, zbxerr.ErrorInvalidConfiguration.Wrap(err)
+	}
+
+	result, err := handlers.TLSCertHandler(ctx, handlers.TLSCertOptions{Addr: u.Addr(), Config: cfg})
+	if err != nil {
+		p.Errf(err.Error())
+
+		return nil, errs.Wrap(err, "failed to check TLS certificate")
+	}
+
+	return result, nil
+}
+
 // Start implements the Runner interface and performs initialization when plugin is activated.
 func (p *Plugin) Start() {
+	p.startConnManager()
+	p.startExporter()
+}
+
+// startConnManager prepares the plugin to handle requests. Unlike Start, it does not start the exporter,
+// so the command line mode can use it without opening a listener.
+func (p *Plugin) startConnManager() {
 	handlers.Logger = p.Logger
+	p.stats = newRequestStats()
+	opts := p.config()
 	p.connMgr = NewConnManager(
-		time.Duration(p.options.KeepAlive)*time.Second,
-		time.Duration(p.options.Timeout)*time.Second,
+		time.Duration(opts.KeepAlive)*time.Second,
+		time.Duration(opts.Timeout)*time.Second,
 		hkInterval*time.Second,
 		p.Logger,
 	)
@@ -120,6 +220,7 @@ func (p *Plugin) Start() {
 
 // Stop implements the Runner interface and frees resources when plugin is deactivated.
 func (p *Plugin) Stop() {
+	p.stopExporter()
 	p.connMgr.Destroy()
 	p.connMgr = nil
 }
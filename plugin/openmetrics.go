@@ -0,0 +1,135 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const (
+	typeGauge   = "gauge"
+	typeCounter = "counter"
+
+	openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+type omSample struct {
+	labels []string
+	value  float64
+}
+
+type omFamily struct {
+	name    string
+	typ     string
+	help    string
+	samples []omSample
+}
+
+// metricWriter collects samples grouped by metric family and writes them in the OpenMetrics text format.
+type metricWriter struct {
+	families map[string]*omFamily
+	order    []string
+}
+
+func newMetricWriter() *metricWriter {
+	return &metricWriter{families: make(map[string]*omFamily)}
+}
+
+// add appends a sample to the family, labels are given as name and value pairs.
+// Counter family names are given without the "_total" suffix, it is added to the samples.
+func (w *metricWriter) add(name, typ, help string, value float64, labels ...string) {
+	f, ok := w.families[name]
+	if !ok {
+		f = &omFamily{name: name, typ: typ, help: help}
+		w.families[name] = f
+		w.order = append(w.order, name)
+	}
+
+	f.samples = append(f.samples, omSample{labels: labels, value: value})
+}
+
+func (w *metricWriter) writeTo(out io.Writer) error {
+	var b strings.Builder
+
+	for _, name := range w.order {
+		f := w.families[name]
+
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.typ)
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, f.help)
+
+		sampleName := f.name
+		if f.typ == typeCounter {
+			sampleName += "_total"
+		}
+
+		for _, s := range f.samples {
+			b.WriteString(sampleName)
+			writeLabels(&b, s.labels)
+			b.WriteByte(' ')
+			b.WriteString(formatValue(s.value))
+			b.WriteByte('\n')
+		}
+	}
+
+	b.WriteString("# EOF\n")
+
+	_, err := io.WriteString(out, b.String())
+
+	return err
+}
+
+func writeLabels(b *strings.Builder, labels []string) {
+	if len(labels) == 0 {
+		return
+	}
+
+	b.WriteByte('{')
+
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteString(labels[i])
+		b.WriteString(`="`)
+		b.WriteString(escapeLabelValue(labels[i+1]))
+		b.WriteByte('"')
+	}
+
+	b.WriteByte('}')
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(v string) string {
+	return labelValueReplacer.Replace(v)
+}
+
+func formatValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
@@ -0,0 +1,217 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.zabbix.com/sdk/errs"
+	"golang.zabbix.com/sdk/zbxerr"
+)
+
+var errRequestTimeout = errs.New("request execution timeout exceeded")
+
+// latencyBuckets are the upper bounds of request latency histogram buckets in milliseconds.
+var latencyBuckets = []float64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+type keyStats struct {
+	count    int64
+	errors   int64
+	timeouts int64
+	sum      time.Duration
+	max      time.Duration
+	buckets  []int64
+}
+
+// requestStats counts requests and their latency per metric key.
+type requestStats struct {
+	mu   sync.Mutex
+	keys map[string]*keyStats
+}
+
+type latencyJSON struct {
+	SumMs   float64          `json:"sumMs"`
+	MaxMs   float64          `json:"maxMs"`
+	Buckets map[string]int64 `json:"buckets"`
+}
+
+type keyStatsJSON struct {
+	Count     int64       `json:"count"`
+	Errors    int64       `json:"errors"`
+	Timeouts  int64       `json:"timeouts"`
+	LatencyMs latencyJSON `json:"latency"`
+}
+
+type connJSON struct {
+	Addr       string `json:"addr"`
+	User       string `json:"user"`
+	TLSConnect string `json:"tlsConnect"`
+	LastAccess int64  `json:"lastAccess"`
+	InUse      int    `json:"inUse"`
+}
+
+type connStatsJSON struct {
+	Open          int        `json:"open"`
+	Created       int64      `json:"created"`
+	Closed        int64      `json:"closed"`
+	Failed        int64      `json:"failed"`
+	CacheHits     int64      `json:"cacheHits"`
+	CacheMisses   int64      `json:"cacheMisses"`
+	CacheHitRatio float64    `json:"cacheHitRatio"`
+	List          []connJSON `json:"list"`
+}
+
+type pluginStatsJSON struct {
+	Connections     connStatsJSON           `json:"connections"`
+	HousekeeperRuns int64                   `json:"housekeeperRuns"`
+	Timeouts        int64                   `json:"timeouts"`
+	Requests        map[string]keyStatsJSON `json:"requests"`
+}
+
+func newRequestStats() *requestStats {
+	return &requestStats{keys: make(map[string]*keyStats)}
+}
+
+// observe records a finished request of the metric key.
+func (s *requestStats) observe(key string, elapsed time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ks, ok := s.keys[key]
+	if !ok {
+		ks = &keyStats{buckets: make([]int64, len(latencyBuckets))}
+		s.keys[key] = ks
+	}
+
+	ks.count++
+	ks.sum += elapsed
+
+	if elapsed > ks.max {
+		ks.max = elapsed
+	}
+
+	if err != nil {
+		ks.errors++
+
+		if errors.Is(err, errRequestTimeout) {
+			ks.timeouts++
+		}
+	}
+
+	ms := float64(elapsed) / float64(time.Millisecond)
+
+	for i, le := range latencyBuckets {
+		if ms <= le {
+			ks.buckets[i]++
+		}
+	}
+}
+
+// snapshot returns the request statistics with cumulative histogram buckets and the total number of timeouts.
+func (s *requestStats) snapshot() (map[string]keyStatsJSON, int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var timeouts int64
+
+	res := make(map[string]keyStatsJSON, len(s.keys))
+
+	for key, ks := range s.keys {
+		buckets := make(map[string]int64, len(latencyBuckets)+1)
+		for i, le := range latencyBuckets {
+			buckets[strconv.FormatFloat(le, 'f', -1, 64)] = ks.buckets[i]
+		}
+
+		buckets["+Inf"] = ks.count
+
+		res[key] = keyStatsJSON{
+			Count:    ks.count,
+			Errors:   ks.errors,
+			Timeouts: ks.timeouts,
+			LatencyMs: latencyJSON{
+				SumMs:   float64(ks.sum) / float64(time.Millisecond),
+				MaxMs:   float64(ks.max) / float64(time.Millisecond),
+				Buckets: buckets,
+			},
+		}
+
+		timeouts += ks.timeouts
+	}
+
+	return res, timeouts
+}
+
+// connStats returns the state of the connection pool.
+func (c *ConnManager) connStats() connStatsJSON {
+	res := connStatsJSON{
+		Created:     c.created.Load(),
+		Closed:      c.closed.Load(),
+		Failed:      c.failed.Load(),
+		CacheHits:   c.hits.Load(),
+		CacheMisses: c.misses.Load(),
+		List:        []connJSON{},
+	}
+
+	if lookups := res.CacheHits + res.CacheMisses; lookups > 0 {
+		res.CacheHitRatio = float64(res.CacheHits) / float64(lookups)
+	}
+
+	c.connectionsMu.Lock()
+	defer c.connectionsMu.Unlock()
+
+	res.Open = len(c.connections)
+
+	for ck, conn := range c.connections {
+		res.List = append(res.List, connJSON{
+			Addr:       conn.addr,
+			User:       ck.uri.User(),
+			TLSConnect: ck.tlsConnect,
+			LastAccess: conn.lastTimeAccess.Unix(),
+			InUse:      conn.inUse,
+		})
+	}
+
+	sort.Slice(res.List, func(i, j int) bool {
+		if res.List[i].Addr != res.List[j].Addr {
+			return res.List[i].Addr < res.List[j].Addr
+		}
+
+		return res.List[i].User < res.List[j].User
+	})
+
+	return res
+}
+
+// pluginStats returns the state of the plugin itself as JSON.
+func (p *Plugin) pluginStats() (any, error) {
+	res := pluginStatsJSON{
+		Connections:     p.connMgr.connStats(),
+		HousekeeperRuns: p.connMgr.housekeeperRuns.Load(),
+	}
+
+	res.Requests, res.Timeouts = p.stats.snapshot()
+
+	jsonRes, err := json.Marshal(res)
+	if err != nil {
+		return nil, zbxerr.ErrorCannotMarshalJSON.Wrap(err)
+	}
+
+	return string(jsonRes), nil
+}
@@ -0,0 +1,130 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.zabbix.com/sdk/log"
+	"golang.zabbix.com/sdk/uri"
+)
+
+func TestRequestStats(t *testing.T) {
+	t.Parallel()
+
+	s := newRequestStats()
+
+	s.observe(keyPing, 3*time.Millisecond, nil)
+	s.observe(keyPing, 40*time.Millisecond, errors.New("fail"))
+	s.observe(keyPing, 20*time.Second, errRequestTimeout)
+
+	got, timeouts := s.snapshot()
+
+	want := map[string]keyStatsJSON{
+		keyPing: {
+			Count:    3,
+			Errors:   2,
+			Timeouts: 1,
+			LatencyMs: latencyJSON{
+				SumMs: 20043,
+				MaxMs: 20000,
+				Buckets: map[string]int64{
+					"5": 1, "10": 1, "25": 1, "50": 2, "100": 2, "250": 2, "500": 2,
+					"1000": 2, "2500": 2, "5000": 2, "10000": 2, "+Inf": 3,
+				},
+			},
+		},
+	}
+
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("snapshot() = %s", diff)
+	}
+
+	if timeouts != 1 {
+		t.Fatalf("snapshot() timeouts = %d, want 1", timeouts)
+	}
+}
+
+func TestPlugin_pluginStats(t *testing.T) {
+	t.Parallel()
+
+	u, err := uri.NewWithCreds("tcp://127.0.0.1:1", "zabbix", "secret", nil)
+	if err != nil {
+		t.Fatalf("failed to parse uri: %v", err)
+	}
+
+	key := createConnKey(*u, map[string]string{tlsConnectParam: "required"})
+
+	p := &Plugin{
+		connMgr: &ConnManager{connections: make(map[connKey]*MongoConn), log: log.New("test")},
+		stats:   newRequestStats(),
+	}
+
+	conn := p.connMgr.setConn(key, newOfflineConn(t))
+	p.connMgr.created.Add(1)
+	p.connMgr.misses.Add(1)
+	p.connMgr.getConn(key)
+	p.connMgr.hits.Add(3)
+
+	_, err = p.Export(keyPluginStats, nil, nil)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	_, err = p.Export(keyPluginStats, []string{"unexpected"}, nil)
+	if err == nil {
+		t.Fatalf("Export() with parameters succeeded")
+	}
+
+	got, err := p.Export(keyPluginStats, nil, nil)
+	if err != nil {
+		t.Fatalf("Export() error = %v", err)
+	}
+
+	var res pluginStatsJSON
+
+	err = json.Unmarshal([]byte(got.(string)), &res)
+	if err != nil {
+		t.Fatalf("failed to unmarshal result: %v", err)
+	}
+
+	wantConns := connStatsJSON{
+		Open:          1,
+		Created:       1,
+		CacheHits:     3,
+		CacheMisses:   1,
+		CacheHitRatio: 0.75,
+		List: []connJSON{{
+			Addr:       "127.0.0.1:1",
+			User:       "zabbix",
+			TLSConnect: "required",
+			LastAccess: conn.lastTimeAccess.Unix(),
+			InUse:      2,
+		}},
+	}
+
+	if diff := cmp.Diff(wantConns, res.Connections); diff != "" {
+		t.Fatalf("pluginStats() connections = %s", diff)
+	}
+
+	stats := res.Requests[keyPluginStats]
+	if stats.Count != 2 || stats.Errors != 1 {
+		t.Fatalf("pluginStats() requests = %+v, want 2 requests with 1 error", stats)
+	}
+}
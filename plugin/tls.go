@@ -0,0 +1,331 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.zabbix.com/sdk/errs"
+	"golang.zabbix.com/sdk/tlsconfig"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// tlsOptions holds the TLS settings that are not covered by tlsconfig.Details.
+type tlsOptions struct {
+	minVersion   uint16
+	cipherSuites []uint16
+	serverName   string
+	keyPassword  string
+	crl          *x509.RevocationList
+}
+
+// newTLSOptions parses and checks the TLS session options.
+func newTLSOptions(minVersion, cipherSuites, serverName, keyPassword, caFile, crlFile string) (*tlsOptions, error) {
+	opts := &tlsOptions{serverName: serverName, keyPassword: keyPassword}
+
+	if minVersion != "" {
+		v, ok := tlsVersions[minVersion]
+		if !ok {
+			return nil, fmt.Errorf("unsupported TLS version %q, must be one of 1.0, 1.1, 1.2 or 1.3", minVersion)
+		}
+
+		opts.minVersion = v
+	}
+
+	if cipherSuites != "" {
+		suites, err := parseCipherSuites(cipherSuites)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.cipherSuites = suites
+	}
+
+	if crlFile != "" {
+		crl, err := loadCRL(crlFile, caFile)
+		if err != nil {
+			return nil, err
+		}
+
+		opts.crl = crl
+	}
+
+	return opts, nil
+}
+
+func tlsOptionsFromParams(params map[string]string) (*tlsOptions, error) {
+	return newTLSOptions(
+		params[tlsMinVersionParam],
+		params[tlsCipherSuitesParam],
+		params[tlsServerNameParam],
+		params[tlsKeyPasswordParam],
+		params[tlsCAParam],
+		params[tlsCRLParam],
+	)
+}
+
+// apply sets the options on the TLS config built from the connection details.
+func (o *tlsOptions) apply(cfg *tls.Config) {
+	if o.minVersion != 0 {
+		cfg.MinVersion = o.minVersion
+	}
+
+	if o.cipherSuites != nil {
+		cfg.CipherSuites = o.cipherSuites
+	}
+
+	if o.serverName != "" {
+		cfg.ServerName = o.serverName
+	}
+
+	if o.crl != nil {
+		verify := cfg.VerifyPeerCertificate
+		crl := o.crl
+
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, chains [][]*x509.Certificate) error {
+			err := checkRevoked(crl, rawCerts)
+			if err != nil {
+				return err
+			}
+
+			if verify != nil {
+				return verify(rawCerts, chains)
+			}
+
+			return nil
+		}
+	}
+}
+
+// certCheckConfig builds the TLS config of the certificate check from the TLS options of the session, the same way
+// connections load the CA file, the client certificate and the revocation list.
+func certCheckConfig(serverName string, params map[string]string) (*tls.Config, error) {
+	tlsOpts, err := tlsOptionsFromParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := &tls.Config{ServerName: serverName} //nolint:gosec // the minimum version is set by the options
+
+	if params[tlsCAParam] != "" {
+		cfg.RootCAs, err = loadCertPool(params[tlsCAParam])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	cfg.Certificates, err = tlsOpts.loadCertificates(&tlsconfig.Details{
+		TlsCertFile: params[tlsCertParam],
+		TlsKeyFile:  params[tlsKeyParam],
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tlsOpts.apply(cfg)
+
+	return cfg, nil
+}
+
+// loadCertificates loads the client certificate, decrypting the private key if a password is set.
+func (o *tlsOptions) loadCertificates(details *tlsconfig.Details) ([]tls.Certificate, error) {
+	if o.keyPassword == "" || details.TlsCertFile == "" || details.TlsKeyFile == "" {
+		return details.LoadCertificates()
+	}
+
+	certPEM, err := os.ReadFile(details.TlsCertFile)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to read TLS cert file")
+	}
+
+	keyPEM, err := os.ReadFile(details.TlsKeyFile)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to read TLS key file")
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, errs.New("no PEM data found in TLS key file")
+	}
+
+	//nolint:staticcheck // legacy PEM encryption is the format produced by 'openssl rsa -aes256'
+	if !x509.IsEncryptedPEMBlock(block) {
+		return nil, errs.New("TLS key file is not encrypted, but a key password is set")
+	}
+
+	der, err := x509.DecryptPEMBlock(block, []byte(o.keyPassword)) //nolint:staticcheck
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to decrypt TLS key file")
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der}))
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to load tls cert and/or key file")
+	}
+
+	return []tls.Certificate{cert}, nil
+}
+
+// loadCertPool reads the PEM encoded certificates of the CA file.
+func loadCertPool(caFile string) (*x509.CertPool, error) {
+	certs, err := loadCACerts(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	for _, c := range certs {
+		pool.AddCert(c)
+	}
+
+	return pool, nil
+}
+
+// loadCACerts reads and parses the PEM encoded certificates of the CA file.
+func loadCACerts(caFile string) ([]*x509.Certificate, error) {
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to read TLS CA file")
+	}
+
+	var certs []*x509.Certificate
+
+	for {
+		var block *pem.Block
+
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		c, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+
+		certs = append(certs, c)
+	}
+
+	if len(certs) == 0 {
+		return nil, errs.New("no certificates found in TLS CA file")
+	}
+
+	return certs, nil
+}
+
+func parseCipherSuites(list string) ([]uint16, error) {
+	known := make(map[string]uint16)
+
+	for _, s := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[s.Name] = s.ID
+	}
+
+	var suites []uint16
+
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown TLS cipher suite %q", name)
+		}
+
+		suites = append(suites, id)
+	}
+
+	return suites, nil
+}
+
+// loadCRL reads a PEM or DER encoded certificate revocation list and checks that it is signed by a certificate of
+// the CA file, as a list nobody signed could be used to hide a revoked certificate.
+func loadCRL(file, caFile string) (*x509.RevocationList, error) {
+	if caFile == "" {
+		return nil, errs.New("TLS CA file is required to verify the TLS CRL file")
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to read TLS CRL file")
+	}
+
+	if block, _ := pem.Decode(data); block != nil {
+		data = block.Bytes
+	}
+
+	crl, err := x509.ParseRevocationList(data)
+	if err != nil {
+		return nil, errs.Wrap(err, "failed to parse TLS CRL file")
+	}
+
+	cas, err := loadCACerts(caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ca := range cas {
+		if crl.CheckSignatureFrom(ca) == nil {
+			return crl, nil
+		}
+	}
+
+	return nil, errs.New("TLS CRL file is not signed by a certificate of the TLS CA file")
+}
+
+// checkRevoked returns an error if any certificate presented by the server is listed in the CRL, or if the CRL is
+// past its next update, as it may miss recently revoked certificates.
+func checkRevoked(crl *x509.RevocationList, rawCerts [][]byte) error {
+	if !crl.NextUpdate.IsZero() && time.Now().After(crl.NextUpdate) {
+		return errors.New("TLS CRL file is out of date, next update was due " + crl.NextUpdate.UTC().Format(time.RFC3339))
+	}
+
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return errs.Wrap(err, "failed to parse server certificate")
+		}
+
+		if cert.Issuer.String() != crl.Issuer.String() {
+			continue
+		}
+
+		for _, entry := range crl.RevokedCertificateEntries {
+			if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return errors.New("server certificate " + cert.Subject.String() + " is revoked")
+			}
+		}
+	}
+
+	return nil
+}
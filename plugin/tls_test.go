@@ -0,0 +1,299 @@
+/*
+** Copyright (C) 2001-2025 Zabbix SIA
+**
+** This program is free software: you can redistribute it and/or modify it under the terms of
+** the GNU Affero General Public License as published by the Free Software Foundation, version 3.
+**
+** This program is distributed in the hope that it will be useful, but WITHOUT ANY WARRANTY;
+** without even the implied warranty of MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.
+** See the GNU Affero General Public License for more details.
+**
+** You should have received a copy of the GNU Affero General Public License along with this program.
+** If not, see <https://www.gnu.org/licenses/>.
+**/
+
+package plugin
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.zabbix.com/plugin/mongodb/plugin/internal/tlstest"
+	"golang.zabbix.com/sdk/log"
+)
+
+// handshake builds the client TLS config the same way connections do and performs a handshake.
+func handshake(addr string, params map[string]string) error {
+	params[uriParam] = "tcp://" + addr
+
+	details, err := createTLS(params)
+	if err != nil {
+		return err
+	}
+
+	tlsOpts, err := tlsOptionsFromParams(params)
+	if err != nil {
+		return err
+	}
+
+	opt := options.Client()
+
+	c := &ConnManager{log: log.New("test")}
+
+	err = c.setTLSConfig(opt, details, tlsOpts)
+	if err != nil {
+		return err
+	}
+
+	conn, err := tls.Dial("tcp", addr, opt.TLSConfig)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	// TLS 1.3 clients finish the handshake before the server checks the client certificate.
+	_, err = conn.Read(make([]byte, 1))
+	if err != nil && err.Error() != "EOF" {
+		return err
+	}
+
+	return nil
+}
+
+func TestTLSOptionsHandshake(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := tlstest.NewCA(t, "Test CA")
+	expires := time.Now().Add(time.Hour)
+	server := ca.Issue(t, 10, "mongo", expires, x509.ExtKeyUsageServerAuth)
+	client := ca.Issue(t, 20, "agent", expires, x509.ExtKeyUsageClientAuth)
+
+	caFile := tlstest.WriteFile(t, dir, "ca.crt", ca.PEM())
+	clientCert, clientKey := tlstest.WriteKeyPair(t, dir, "client", client, "secret")
+	revokedCRL := tlstest.WriteFile(t, dir, "revoked.crl", ca.CRL(t, expires, 10))
+	otherCRL := tlstest.WriteFile(t, dir, "other.crl", ca.CRL(t, expires, 11, 12))
+	staleCRL := tlstest.WriteFile(t, dir, "stale.crl", ca.CRL(t, time.Now().Add(-time.Minute), 11))
+	untrustedCRL := tlstest.WriteFile(t, dir, "untrusted.crl", tlstest.NewCA(t, "Other CA").CRL(t, expires, 11))
+
+	tls12 := tlstest.StartServer(t, &tls.Config{
+		Certificates: []tls.Certificate{server},
+		MaxVersion:   tls.VersionTLS12,
+		CipherSuites: []uint16{tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384},
+	})
+	mutual := tlstest.StartServer(t, &tls.Config{
+		Certificates: []tls.Certificate{server},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    ca.Pool(),
+		MinVersion:   tls.VersionTLS12,
+	})
+
+	verifyFull := func(extra map[string]string) map[string]string {
+		params := map[string]string{
+			tlsConnectParam:    "verify_full",
+			tlsCAParam:         caFile,
+			tlsServerNameParam: "mongo.example.com",
+		}
+
+		for k, v := range extra {
+			params[k] = v
+		}
+
+		return params
+	}
+
+	tests := []struct {
+		name    string
+		addr    string
+		params  map[string]string
+		wantErr bool
+	}{
+		{"+verifyFull", tls12, verifyFull(nil), false},
+		{"+uriHost", tls12, verifyFull(map[string]string{tlsServerNameParam: ""}), false},
+		{"-wrongServerName", tls12, verifyFull(map[string]string{tlsServerNameParam: "other.example.com"}), true},
+		{"+minVersion12", tls12, verifyFull(map[string]string{tlsMinVersionParam: "1.2"}), false},
+		{"-minVersion13", tls12, verifyFull(map[string]string{tlsMinVersionParam: "1.3"}), true},
+		{
+			"+cipherSuite",
+			tls12,
+			verifyFull(map[string]string{
+				tlsCipherSuitesParam: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256, TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384",
+			}),
+			false,
+		},
+		{
+			"-cipherSuiteMismatch",
+			tls12,
+			verifyFull(map[string]string{tlsCipherSuitesParam: "TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256"}),
+			true,
+		},
+		{"+crlNotRevoked", tls12, verifyFull(map[string]string{tlsCRLParam: otherCRL}), false},
+		{"-crlRevoked", tls12, verifyFull(map[string]string{tlsCRLParam: revokedCRL}), true},
+		{"-crlStale", tls12, verifyFull(map[string]string{tlsCRLParam: staleCRL}), true},
+		{"-crlUntrusted", tls12, verifyFull(map[string]string{tlsCRLParam: untrustedCRL}), true},
+		{
+			"-crlRevokedRequired",
+			tls12,
+			map[string]string{tlsConnectParam: "required", tlsCRLParam: revokedCRL},
+			true,
+		},
+		{
+			"+encryptedKey",
+			mutual,
+			verifyFull(map[string]string{
+				tlsCertParam: clientCert, tlsKeyParam: clientKey, tlsKeyPasswordParam: "secret",
+			}),
+			false,
+		},
+		{
+			"-wrongKeyPassword",
+			mutual,
+			verifyFull(map[string]string{
+				tlsCertParam: clientCert, tlsKeyParam: clientKey, tlsKeyPasswordParam: "wrong",
+			}),
+			true,
+		},
+		{
+			"-encryptedKeyNoPassword",
+			mutual,
+			verifyFull(map[string]string{tlsCertParam: clientCert, tlsKeyParam: clientKey}),
+			true,
+		},
+		{"-noClientCert", mutual, verifyFull(nil), true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := handshake(tt.addr, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("handshake() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewTLSOptions(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := tlstest.NewCA(t, "Test CA")
+	expires := time.Now().Add(time.Hour)
+	caFile := tlstest.WriteFile(t, dir, "ca.crt", ca.PEM())
+	crl := tlstest.WriteFile(t, dir, "ca.crl", ca.CRL(t, expires, 1))
+	otherCRL := tlstest.WriteFile(t, dir, "other.crl", tlstest.NewCA(t, "Other CA").CRL(t, expires, 1))
+	notCRL := tlstest.WriteFile(t, dir, "bad.crl", []byte("not a CRL"))
+
+	tests := []struct {
+		name         string
+		minVersion   string
+		cipherSuites string
+		caFile       string
+		crlFile      string
+		wantErr      bool
+	}{
+		{"+empty", "", "", "", "", false},
+		{"+all", "1.2", "TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", caFile, crl, false},
+		{"-minVersion", "1.4", "", "", "", true},
+		{"-cipherSuite", "", "TLS_UNKNOWN", "", "", true},
+		{"-missingCRL", "", "", caFile, filepath.Join(dir, "missing.crl"), true},
+		{"-invalidCRL", "", "", caFile, notCRL, true},
+		{"-crlWithoutCAFile", "", "", "", crl, true},
+		{"-crlOtherCA", "", "", caFile, otherCRL, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := newTLSOptions(tt.minVersion, tt.cipherSuites, "", "", tt.caFile, tt.crlFile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newTLSOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCertCheckConfig(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	ca := tlstest.NewCA(t, "Test CA")
+	expires := time.Now().Add(time.Hour)
+	server := ca.Issue(t, 10, "mongo", expires, x509.ExtKeyUsageServerAuth)
+	client := ca.Issue(t, 20, "agent", expires, x509.ExtKeyUsageClientAuth)
+
+	caFile := tlstest.WriteFile(t, dir, "ca.crt", ca.PEM())
+	clientCert, clientKey := tlstest.WriteKeyPair(t, dir, "client", client, "secret")
+	revokedCRL := tlstest.WriteFile(t, dir, "revoked.crl", ca.CRL(t, expires, 10))
+
+	tests := []struct {
+		name        string
+		params      map[string]string
+		wantCerts   int
+		wantMin     uint16
+		wantRevoked bool
+		wantErr     bool
+	}{
+		{"+empty", map[string]string{}, 0, 0, false, false},
+		{
+			"+encryptedKey",
+			map[string]string{
+				tlsCAParam: caFile, tlsCertParam: clientCert, tlsKeyParam: clientKey, tlsKeyPasswordParam: "secret",
+			},
+			1, 0, false, false,
+		},
+		{"+minVersion", map[string]string{tlsMinVersionParam: "1.3"}, 0, tls.VersionTLS13, false, false},
+		{"+crl", map[string]string{tlsCAParam: caFile, tlsCRLParam: revokedCRL}, 0, 0, true, false},
+		{
+			"-wrongKeyPassword",
+			map[string]string{tlsCertParam: clientCert, tlsKeyParam: clientKey, tlsKeyPasswordParam: "wrong"},
+			0, 0, false, true,
+		},
+		{"-missingCAFile", map[string]string{tlsCAParam: filepath.Join(dir, "missing.crt")}, 0, 0, false, true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := certCheckConfig("mongo.example.com", tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("certCheckConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if cfg.ServerName != "mongo.example.com" {
+				t.Errorf("ServerName = %q, want mongo.example.com", cfg.ServerName)
+			}
+
+			if len(cfg.Certificates) != tt.wantCerts {
+				t.Errorf("Certificates = %d, want %d", len(cfg.Certificates), tt.wantCerts)
+			}
+
+			if cfg.MinVersion != tt.wantMin {
+				t.Errorf("MinVersion = %x, want %x", cfg.MinVersion, tt.wantMin)
+			}
+
+			if (cfg.VerifyPeerCertificate != nil) != tt.wantRevoked {
+				t.Fatalf("VerifyPeerCertificate set = %v, want %v", cfg.VerifyPeerCertificate != nil, tt.wantRevoked)
+			}
+
+			if tt.wantRevoked && cfg.VerifyPeerCertificate(server.Certificate, nil) == nil {
+				t.Errorf("VerifyPeerCertificate() accepted a revoked certificate")
+			}
+		})
+	}
+}